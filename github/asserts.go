@@ -39,8 +39,8 @@ func CreateAssert(t *ErrorSlice) *assert.Assertions {
 }
 
 func AssertFileDoesNotExist(path string) error {
-	if _, err := os.Stat("/path/to/whatever"); os.IsNotExist(err) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil
 	}
-	return fmt.Errorf("The path %s exists!")
+	return fmt.Errorf("The path %s exists!", path)
 }
@@ -0,0 +1,156 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/google/go-github/github"
+)
+
+const checkRunsMediaType = "application/vnd.github.antiope-preview+json"
+
+// CheckRun represents a GitHub check run reported against a commit
+type CheckRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+// CheckRunsResult is the result of listing the check runs of a commit
+type CheckRunsResult struct {
+	TotalCount int         `json:"total_count"`
+	CheckRuns  []*CheckRun `json:"check_runs"`
+}
+
+// GetCombinedStatus returns the combined commit status for the given git ref with the statuses of all of its pages
+func GetCombinedStatus(client *github.Client, userRepo *UserRepositoryName, ref string) (*github.CombinedStatus, error) {
+	ctx := context.Background()
+	opts := &github.ListOptions{
+		PerPage: 100,
+	}
+	var answer *github.CombinedStatus
+	for {
+		combined, resp, err := client.Repositories.GetCombinedStatus(ctx, userRepo.Organisation, userRepo.Repository, ref, opts)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get the commit status of %s on %s due to %v", ref, userRepo.String(), err)
+		}
+		if answer == nil {
+			answer = combined
+		} else {
+			answer.Statuses = append(answer.Statuses, combined.Statuses...)
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return answer, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// GetCommitStatus returns the latest commit status with the given context for the git ref or nil
+// if there is no status reported for that context yet
+func GetCommitStatus(client *github.Client, userRepo *UserRepositoryName, ref string, statusContext string) (*github.RepoStatus, error) {
+	combined, err := GetCombinedStatus(client, userRepo, ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range combined.Statuses {
+		if status.Context != nil && *status.Context == statusContext {
+			s := status
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListCheckRuns returns the check runs reported for the given git ref from all of the pages
+func ListCheckRuns(client *github.Client, userRepo *UserRepositoryName, ref string) ([]*CheckRun, error) {
+	ctx := context.Background()
+	checkRuns := []*CheckRun{}
+	page := 1
+	for {
+		u := fmt.Sprintf("repos/%s/%s/commits/%s/check-runs?per_page=100&page=%d", userRepo.Organisation, userRepo.Repository, ref, page)
+		req, err := client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", checkRunsMediaType)
+
+		result := &CheckRunsResult{}
+		resp, err := client.Do(ctx, req, result)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list the check runs of %s on %s due to %v", ref, userRepo.String(), err)
+		}
+		checkRuns = append(checkRuns, result.CheckRuns...)
+		if resp == nil || resp.NextPage == 0 {
+			return checkRuns, nil
+		}
+		page = resp.NextPage
+	}
+}
+
+// GetCheckRun returns the check run with the given name for the git ref or nil if there is none yet
+func GetCheckRun(client *github.Client, userRepo *UserRepositoryName, ref string, name string) (*CheckRun, error) {
+	checkRuns, err := ListCheckRuns(client, userRepo, ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, checkRun := range checkRuns {
+		if checkRun.Name == name {
+			return checkRun, nil
+		}
+	}
+	return nil, nil
+}
+
+// WaitForCommitStatus waits for the commit status with the given context to have the expected state.
+// A status which is still pending is polled until the timeout; any other state fails immediately
+func WaitForCommitStatus(c *utils.ScenarioContext, client *github.Client, userRepo *UserRepositoryName, ref string, statusContext string, expectedState string, timeout time.Duration) error {
+	state := ""
+	fn := func() (bool, error) {
+		status, err := GetCommitStatus(client, userRepo, ref, statusContext)
+		if err != nil {
+			return false, err
+		}
+		if status == nil || status.State == nil {
+			return false, nil
+		}
+		if state != *status.State {
+			state = *status.State
+			c.LogInfof("commit %s on %s has status %s = %s\n", ref, userRepo.String(), statusContext, state)
+		}
+		if state == expectedState {
+			return true, nil
+		}
+		if state == "pending" {
+			return false, nil
+		}
+		return false, fmt.Errorf("Commit %s on %s has status %s = %s but expected %s", ref, userRepo.String(), statusContext, state, expectedState)
+	}
+	message := fmt.Sprintf("commit %s on %s to have status %s = %s", ref, userRepo.String(), statusContext, expectedState)
+	return c.PollPhase(utils.TimeoutStatusCheck, 5*time.Second, timeout, message, fn)
+}
+
+// WaitForCheckRun waits for the check run with the given name to complete with the expected conclusion
+func WaitForCheckRun(c *utils.ScenarioContext, client *github.Client, userRepo *UserRepositoryName, ref string, name string, expectedConclusion string, timeout time.Duration) error {
+	fn := func() (bool, error) {
+		checkRun, err := GetCheckRun(client, userRepo, ref, name)
+		if err != nil {
+			return false, err
+		}
+		if checkRun == nil || checkRun.Status != "completed" {
+			return false, nil
+		}
+		c.LogInfof("commit %s on %s has check run %s with conclusion %s\n", ref, userRepo.String(), name, checkRun.Conclusion)
+		if checkRun.Conclusion == expectedConclusion {
+			return true, nil
+		}
+		return false, fmt.Errorf("Commit %s on %s has check run %s with conclusion %s but expected %s", ref, userRepo.String(), name, checkRun.Conclusion, expectedConclusion)
+	}
+	message := fmt.Sprintf("commit %s on %s to have check run %s with conclusion %s", ref, userRepo.String(), name, expectedConclusion)
+	return c.PollPhase(utils.TimeoutStatusCheck, 5*time.Second, timeout, message, fn)
+}
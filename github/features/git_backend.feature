@@ -0,0 +1,20 @@
+Feature: git backends
+  In order to run the tests without interactive prompts
+  As a tester
+  I need to be able to choose the git backend used to clone, commit, tag and push
+
+  Scenario Outline: Git backend works against a local bare repository
+    Given a local bare git repository containing "README.md"
+    When I clone the local repository using the "<backend>" git backend
+    And I commit a change to "README.md" on branch "feature-x"
+    And I push branch "feature-x" to the local repository
+    Then the local repository branch "feature-x" should have the same last commit as the clone
+    When I tag and push the clone with "v1.0.0"
+    Then the local repository should have tag "v1.0.0" on the last commit of the clone
+    And I reset the clone to branch "feature-x"
+    And the file "README.md" in the clone should contain "changed content"
+
+    Examples:
+      | backend |
+      | exec    |
+      | go-git  |
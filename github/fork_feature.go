@@ -1,15 +1,31 @@
 package github
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DATA-DOG/godog/gherkin"
 	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/google/go-github/github"
 )
 
 type ForkFeature struct {
+	Context      *utils.ScenarioContext
 	GitCommander *GitCommander
 
 	UpstreamDir    string
 	ForkDir        string
 	ForkedRepoName string
+
+	// Branch is the branch to reset from the upstream repository which defaults to its default branch
+	Branch string
+	// DefaultBranch is the default branch of the upstream repository
+	DefaultBranch string
+
+	// PushedBranch is the branch of the fork which was last pushed to GitHub and PushedSha is its last commit
+	PushedBranch string
+	PushedSha    string
 }
 
 // ForkToUsersRepo forks the given upstream repo cleanly to the current github users account
@@ -24,7 +40,7 @@ func (f *ForkFeature) ForkToUsersRepo(uptreamRepoName string) (string, error) {
 		return "", err
 	}
 	name := f.ForkedRepoName
-	utils.LogInfof("forked the repository %s to the current users account %s\n", uptreamRepoName, name)
+	f.Context.LogInfof("forked the repository %s to the current users account %s\n", uptreamRepoName, name)
 	return name, err
 }
 
@@ -38,7 +54,9 @@ func (f *ForkFeature) iForkTheGitHubOrganisationToTheCurrentUser(originalRepoNam
 		return err
 	}
 	f.ForkedRepoName = currentGithubUser + "/" + userRepo.Repository
-	client, err := CreateGitHubClient()
+	// scenarios in other features reset the same fork so they wait for this scenario to finish with it
+	f.Context.LockResource("fork " + f.ForkedRepoName)
+	client, err := CreateGitHubClient(f.Context)
 	if err != nil {
 		return err
 	}
@@ -48,9 +66,15 @@ func (f *ForkFeature) iForkTheGitHubOrganisationToTheCurrentUser(originalRepoNam
 	if err != nil {
 		return err
 	}
+	f.DefaultBranch = GetDefaultBranch(upstreamRepo)
+	f.Context.LogInfof("the default branch of %s is %s\n", originalRepoName, f.DefaultBranch)
 
 	// now lets fork it
-	repo, err := ForkRepositoryOrRevertMasterInFork(client, userRepo, currentGithubUser)
+	repo, err := ForkRepositoryOrRevertMasterInFork(f.Context, client, userRepo, currentGithubUser)
+	if err != nil {
+		return err
+	}
+	err = f.WaitForForkToBeCloneable(repo)
 	if err != nil {
 		return err
 	}
@@ -58,7 +82,7 @@ func (f *ForkFeature) iForkTheGitHubOrganisationToTheCurrentUser(originalRepoNam
 	if err != nil {
 		return err
 	}
-	utils.LogInfof("Cloned to directory: %s\n", dir)
+	f.Context.LogInfof("Cloned to directory: %s\n", dir)
 	f.ForkDir = dir
 
 	upstreamCloneURL, err := GetCloneURL(upstreamRepo, true)
@@ -72,10 +96,184 @@ func (f *ForkFeature) iForkTheGitHubOrganisationToTheCurrentUser(originalRepoNam
 	}
 	f.UpstreamDir = upstreamDir
 
-	err = gitcmder.ResetMasterFromUpstream(dir, upstreamCloneURL)
+	err = gitcmder.ResetBranchFromUpstream(dir, upstreamCloneURL, f.ResetBranch())
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ResetBranch returns the branch which is reset from the upstream repository
+func (f *ForkFeature) ResetBranch() string {
+	if f.Branch != "" {
+		return f.Branch
+	}
+	if f.DefaultBranch != "" {
+		return f.DefaultBranch
+	}
+	return defaultBranch
+}
+
+// Variables returns the variables of the fork which can be used in expressions
+func (f *ForkFeature) Variables() map[string]string {
+	return map[string]string{
+		DefaultBranchVariable: f.ResetBranch(),
+	}
+}
+
+// WaitForForkToBeReady waits for the fork to exist on GitHub with its default branch and to be cloneable
+func (f *ForkFeature) WaitForForkToBeReady() error {
+	forkRepo, err := ParseUserRepositoryName(f.ForkedRepoName)
+	if err != nil {
+		return err
+	}
+	client, err := CreateGitHubClient(f.Context)
+	if err != nil {
+		return err
+	}
+	repo, err := WaitForRepositoryToBeReady(f.Context, client, forkRepo, ForkReadyTimeout(f.Context))
+	if err != nil {
+		return err
+	}
+	return f.WaitForForkToBeCloneable(repo)
+}
+
+// WaitForForkToBeCloneable waits for the default branch of the fork to be cloneable
+func (f *ForkFeature) WaitForForkToBeCloneable(repo *github.Repository) error {
+	gitcmder := f.GitCommander
+	cloneURL, err := gitcmder.GetCloneURL(repo)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(gitcmder.Dir, 0770)
+	if err != nil {
+		return err
+	}
+	return gitcmder.WaitForCloneable(cloneURL, repo.GetDefaultBranch(), ForkReadyTimeout(f.Context))
+}
+
+// CreateBranch creates a new branch in the fork and checks it out
+func (f *ForkFeature) CreateBranch(branch string) error {
+	return f.GitCommander.CreateBranch(f.ForkDir, branch)
+}
+
+// ChangeFile replaces the content of the file in the fork
+func (f *ForkFeature) ChangeFile(path string, content string) error {
+	return f.GitCommander.WriteFile(f.ForkDir, path, content)
+}
+
+// AppendToFile appends the content to the file in the fork creating it if it does not exist
+func (f *ForkFeature) AppendToFile(path string, content string) error {
+	fileName := filepath.Join(f.ForkDir, path)
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0660)
+	if err != nil {
+		return fmt.Errorf("Failed to open file %s due to %v", fileName, err)
+	}
+	defer file.Close()
+	_, err = file.WriteString(content)
+	if err != nil {
+		return fmt.Errorf("Failed to change file %s due to %v", fileName, err)
+	}
+	return nil
+}
+
+// Commit commits all of the changes in the fork
+func (f *ForkFeature) Commit(message string) error {
+	return f.GitCommander.CommitAll(f.ForkDir, message)
+}
+
+// Tag creates an annotated tag of the last commit in the fork
+func (f *ForkFeature) Tag(tag string) error {
+	return f.GitCommander.Tag(f.ForkDir, tag, "godog tagging "+tag)
+}
+
+// PushBranch pushes the branch of the fork to GitHub and returns the SHA of the last commit
+func (f *ForkFeature) PushBranch(branch string) (string, error) {
+	err := f.GitCommander.Push(f.ForkDir, branch)
+	if err != nil {
+		return "", err
+	}
+	sha, err := f.GitCommander.GetLastCommitSha(f.ForkDir)
+	if err != nil {
+		return "", err
+	}
+	f.PushedBranch = branch
+	f.PushedSha = sha
+	return sha, nil
+}
+
+// PushTag pushes the tag of the fork to GitHub
+func (f *ForkFeature) PushTag(tag string) error {
+	return f.GitCommander.PushTag(f.ForkDir, tag)
+}
+
+// ForkGitSteps registers the steps which change, commit, tag and push the fork returned by the function so that
+// the features which create a fork share them
+func ForkGitSteps(c *utils.ScenarioContext, fork func() (*ForkFeature, error)) {
+	forked := func() (*ForkFeature, error) {
+		f, err := fork()
+		if err != nil {
+			return nil, err
+		}
+		if f == nil || f.ForkDir == "" {
+			return nil, fmt.Errorf("No fork has been created yet")
+		}
+		return f, nil
+	}
+
+	c.Step(`^the git author is "([^"]*)" with email "([^"]*)"$`, func(name string, email string) error {
+		f, err := forked()
+		if err != nil {
+			return err
+		}
+		f.GitCommander.Author = &GitAuthor{
+			Name:  name,
+			Email: email,
+		}
+		return nil
+	})
+	c.Step(`^I create branch "([^"]*)" in the fork$`, func(branch string) error {
+		f, err := forked()
+		if err != nil {
+			return err
+		}
+		return f.CreateBranch(c.ReplaceVariables(branch, f.Variables()))
+	})
+	c.Step(`^I change "([^"]*)" in the fork to:$`, func(path string, content *gherkin.DocString) error {
+		f, err := forked()
+		if err != nil {
+			return err
+		}
+		return f.ChangeFile(path, content.Content+"\n")
+	})
+	c.Step(`^I commit the changes in the fork with message "([^"]*)"$`, func(message string) error {
+		f, err := forked()
+		if err != nil {
+			return err
+		}
+		return f.Commit(message)
+	})
+	c.Step(`^I tag the fork with "([^"]*)"$`, func(tag string) error {
+		f, err := forked()
+		if err != nil {
+			return err
+		}
+		return f.Tag(c.ReplaceVariables(tag, f.Variables()))
+	})
+	c.Step(`^I push branch "([^"]*)"$`, func(branch string) error {
+		f, err := forked()
+		if err != nil {
+			return err
+		}
+		_, err = f.PushBranch(c.ReplaceVariables(branch, f.Variables()))
+		return err
+	})
+	c.Step(`^I push tag "([^"]*)"$`, func(tag string) error {
+		f, err := forked()
+		if err != nil {
+			return err
+		}
+		return f.PushTag(c.ReplaceVariables(tag, f.Variables()))
+	})
+}
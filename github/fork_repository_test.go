@@ -1,6 +1,7 @@
 package github
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 
@@ -29,8 +30,8 @@ func (f *ForkFeature) thereShouldBeAForkForTheCurrentUserWhichHasTheSameLastComm
 	if err != nil {
 		return err
 	}
-	utils.LogInfof("upstream last commit is %s\n", upstreamSha)
-	utils.LogInfof("fork last commit is %s\n", forkSha)
+	f.Context.LogInfof("upstream last commit is %s\n", upstreamSha)
+	f.Context.LogInfof("fork last commit is %s\n", forkSha)
 
 	errors := CreateErrorSlice()
 	assert := CreateAssert(errors)
@@ -40,12 +41,104 @@ func (f *ForkFeature) thereShouldBeAForkForTheCurrentUserWhichHasTheSameLastComm
 	return errors.Error()
 }
 
+func (f *ForkFeature) theForkResetsBranchFromUpstream(branch string) error {
+	f.Branch = branch
+	return nil
+}
+
+func (f *ForkFeature) theDefaultBranchOfTheForkShouldBe(expected string) error {
+	forkRepo, err := ParseUserRepositoryName(f.ForkedRepoName)
+	if err != nil {
+		return err
+	}
+	client, err := CreateGitHubClient(f.Context)
+	if err != nil {
+		return err
+	}
+	repo, _, err := client.Repositories.Get(context.Background(), forkRepo.Organisation, forkRepo.Repository)
+	if err != nil {
+		return fmt.Errorf("Failed to find the fork %s due to %v", f.ForkedRepoName, err)
+	}
+	actual := repo.GetDefaultBranch()
+	if actual != expected {
+		return fmt.Errorf("The default branch of %s is %s but expected %s", f.ForkedRepoName, actual, expected)
+	}
+	return nil
+}
+
+func (f *ForkFeature) theForkShouldBeReady() error {
+	return f.WaitForForkToBeReady()
+}
+
+func (f *ForkFeature) theLastCommitOfTheForkShouldHaveStatusWithin(statusContext string, expectedState string, amount int, unit string) error {
+	timeout, err := utils.ParseDuration(amount, unit)
+	if err != nil {
+		return err
+	}
+	forkRepo, sha, err := f.forkLastCommit()
+	if err != nil {
+		return err
+	}
+	client, err := CreateGitHubClient(f.Context)
+	if err != nil {
+		return err
+	}
+	return WaitForCommitStatus(f.Context, client, forkRepo, sha, statusContext, expectedState, timeout)
+}
+
+func (f *ForkFeature) theLastCommitOfTheForkShouldHaveCheckRunWithin(name string, expectedConclusion string, amount int, unit string) error {
+	timeout, err := utils.ParseDuration(amount, unit)
+	if err != nil {
+		return err
+	}
+	forkRepo, sha, err := f.forkLastCommit()
+	if err != nil {
+		return err
+	}
+	client, err := CreateGitHubClient(f.Context)
+	if err != nil {
+		return err
+	}
+	return WaitForCheckRun(f.Context, client, forkRepo, sha, name, expectedConclusion, timeout)
+}
+
+func (f *ForkFeature) forkLastCommit() (*UserRepositoryName, string, error) {
+	forkRepo, err := ParseUserRepositoryName(f.ForkedRepoName)
+	if err != nil {
+		return nil, "", err
+	}
+	sha, err := f.GitCommander.GetLastCommitSha(f.ForkDir)
+	return forkRepo, sha, err
+}
+
 func FeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
 	f := &ForkFeature{
-		GitCommander: CreateGitCommander(),
+		Context:      c,
+		GitCommander: CreateGitCommander(c),
 	}
 
-	s.Step(`^there is no fork of "([^"]*)"$`, f.thereIsNoForkOf)
-	s.Step(`^I fork the "([^"]*)" GitHub organisation to the current user$`, f.iForkTheGitHubOrganisationToTheCurrentUser)
-	s.Step(`^there should be a fork for the current user which has the same last commit as "([^"]*)"$`, f.thereShouldBeAForkForTheCurrentUserWhichHasTheSameLastCommitAs)
+	s.BeforeScenario(func(interface{}) {
+		f.GitCommander = CreateGitCommander(c)
+		f.Branch = ""
+		f.DefaultBranch = ""
+		f.ForkDir = ""
+		f.PushedBranch = ""
+		f.PushedSha = ""
+		c.AddCleanup(func() {
+			LogRateLimit(c)
+		})
+	})
+
+	c.Step(`^there is no fork of "([^"]*)"$`, f.thereIsNoForkOf)
+	c.Step(`^I fork the "([^"]*)" GitHub organisation to the current user$`, f.iForkTheGitHubOrganisationToTheCurrentUser)
+	c.Step(`^there should be a fork for the current user which has the same last commit as "([^"]*)"$`, f.thereShouldBeAForkForTheCurrentUserWhichHasTheSameLastCommitAs)
+	c.Step(`^the fork resets branch "([^"]*)" from upstream$`, f.theForkResetsBranchFromUpstream)
+	c.Step(`^the fork should be ready$`, f.theForkShouldBeReady)
+	c.Step(`^the default branch of the fork should be "([^"]*)"$`, f.theDefaultBranchOfTheForkShouldBe)
+	ForkGitSteps(c, func() (*ForkFeature, error) {
+		return f, nil
+	})
+	c.Step(`^the last commit of the fork should have status "([^"]*)" = (\w+) within (\d+) (seconds?|minutes?)$`, f.theLastCommitOfTheForkShouldHaveStatusWithin)
+	c.Step(`^the last commit of the fork should have check run "([^"]*)" = (\w+) within (\d+) (seconds?|minutes?)$`, f.theLastCommitOfTheForkShouldHaveCheckRunWithin)
 }
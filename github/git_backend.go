@@ -0,0 +1,163 @@
+package github
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+const (
+	// ExecGitBackendName is the name of the backend which runs the `git` binary
+	ExecGitBackendName = "exec"
+	// GoGitBackendName is the name of the in process backend implemented using go-git
+	GoGitBackendName = "go-git"
+)
+
+// GitBackend performs the git operations used by the GitCommander
+type GitBackend interface {
+	// Clone clones the given URL into the directory
+	Clone(cloneURL string, dir string) error
+	// AddRemote adds a remote with the given name and URL
+	AddRemote(dir string, name string, url string) error
+	// Fetch fetches from the given remote
+	Fetch(dir string, remote string) error
+	// Checkout checks out the given branch, creating a local branch from the origin remote if required
+	Checkout(dir string, branch string) error
+	// CreateBranch creates a new local branch from HEAD and checks it out
+	CreateBranch(dir string, branch string) error
+	// ResetHard resets the current branch and working tree to the given revision
+	ResetHard(dir string, rev string) error
+	// CommitAll adds all of the changes in the directory and commits them using the author if it is not nil
+	CommitAll(dir string, message string, author *GitAuthor) error
+	// Tag creates an annotated tag of HEAD using the author if it is not nil
+	Tag(dir string, tag string, message string, author *GitAuthor) error
+	// Push pushes the branch to the given remote
+	Push(dir string, remote string, branch string, force bool) error
+	// PushTag pushes the tag to the given remote
+	PushTag(dir string, remote string, tag string) error
+	// RevParse returns the SHA of the given revision
+	RevParse(dir string, rev string) (string, error)
+	// CheckRemoteBranch returns an error if the branch of the given URL cannot be read
+	CheckRemoteBranch(dir string, cloneURL string, branch string) error
+}
+
+// GitAuthor is the author of commits and tags
+type GitAuthor struct {
+	Name  string
+	Email string
+}
+
+// CreateGitAuthor returns the author configured by $BDD_GIT_AUTHOR_NAME and $BDD_GIT_AUTHOR_EMAIL or nil
+// if no author is configured so that the defaults of the git backend are used
+func CreateGitAuthor() *GitAuthor {
+	name := os.Getenv("BDD_GIT_AUTHOR_NAME")
+	email := os.Getenv("BDD_GIT_AUTHOR_EMAIL")
+	if name == "" && email == "" {
+		return nil
+	}
+	return &GitAuthor{
+		Name:  name,
+		Email: email,
+	}
+}
+
+// CreateGitBackend creates the GitBackend named by $BDD_GIT_BACKEND which defaults to the exec backend
+func CreateGitBackend(c *utils.ScenarioContext) (GitBackend, error) {
+	return CreateGitBackendNamed(c, os.Getenv("BDD_GIT_BACKEND"))
+}
+
+// CreateGitBackendNamed creates the GitBackend with the given name which defaults to the exec backend.
+// The backend logs and records its commands against the running scenario of the context
+func CreateGitBackendNamed(c *utils.ScenarioContext, name string) (GitBackend, error) {
+	switch name {
+	case "", ExecGitBackendName:
+		return &execGitBackend{context: c}, nil
+	case GoGitBackendName:
+		return NewGoGitBackend(c)
+	}
+	return nil, fmt.Errorf("Unknown $BDD_GIT_BACKEND %s. Expected %s or %s", name, ExecGitBackendName, GoGitBackendName)
+}
+
+// execGitBackend runs the `git` binary
+type execGitBackend struct {
+	context *utils.ScenarioContext
+}
+
+func (b *execGitBackend) Clone(cloneURL string, dir string) error {
+	return runCommand(b.context, filepath.Dir(dir), "git", "clone", cloneURL, filepath.Base(dir))
+}
+
+func (b *execGitBackend) AddRemote(dir string, name string, url string) error {
+	return runCommand(b.context, dir, "git", "remote", "add", name, url)
+}
+
+func (b *execGitBackend) Fetch(dir string, remote string) error {
+	return runCommand(b.context, dir, "git", "fetch", remote)
+}
+
+func (b *execGitBackend) Checkout(dir string, branch string) error {
+	return runCommand(b.context, dir, "git", "checkout", branch)
+}
+
+func (b *execGitBackend) CreateBranch(dir string, branch string) error {
+	return runCommand(b.context, dir, "git", "checkout", "-b", branch)
+}
+
+func (b *execGitBackend) ResetHard(dir string, rev string) error {
+	return runCommand(b.context, dir, "git", "reset", "--hard", rev)
+}
+
+func (b *execGitBackend) CommitAll(dir string, message string, author *GitAuthor) error {
+	err := runCommand(b.context, dir, "git", "add", "-A")
+	if err != nil {
+		return err
+	}
+	args := append(authorConfigArgs(author), "commit", "-m", message)
+	return runCommand(b.context, dir, "git", args...)
+}
+
+func (b *execGitBackend) Tag(dir string, tag string, message string, author *GitAuthor) error {
+	args := append(authorConfigArgs(author), "tag", "-a", tag, "-m", message)
+	return runCommand(b.context, dir, "git", args...)
+}
+
+func (b *execGitBackend) Push(dir string, remote string, branch string, force bool) error {
+	args := []string{"push", remote, branch}
+	if force {
+		args = append(args, "--force")
+	}
+	return runCommand(b.context, dir, "git", args...)
+}
+
+func (b *execGitBackend) PushTag(dir string, remote string, tag string) error {
+	return runCommand(b.context, dir, "git", "push", remote, "refs/tags/"+tag)
+}
+
+func (b *execGitBackend) RevParse(dir string, rev string) (string, error) {
+	text, err := commandAsString(b.context, dir, "git", "rev-parse", rev)
+	if err != nil {
+		return text, err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+func (b *execGitBackend) CheckRemoteBranch(dir string, cloneURL string, branch string) error {
+	return runCommandQuietly(b.context, dir, "git", "ls-remote", "--exit-code", "--heads", cloneURL, branch)
+}
+
+// authorConfigArgs returns the git arguments to use the given author for commits and tags
+func authorConfigArgs(author *GitAuthor) []string {
+	args := []string{}
+	if author != nil {
+		if author.Name != "" {
+			args = append(args, "-c", "user.name="+author.Name)
+		}
+		if author.Email != "" {
+			args = append(args, "-c", "user.email="+author.Email)
+		}
+	}
+	return args
+}
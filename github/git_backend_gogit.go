@@ -0,0 +1,414 @@
+package github
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+const defaultGitAuthorName = "godog-jenkins"
+
+// GoGitBackend is an in process GitBackend using go-git which uses explicit credentials
+// so that it never blocks on an interactive prompt
+type GoGitBackend struct {
+	// Username and Password are used for http and https URLs
+	Username string
+	Password string
+	// SSHKeyFile and SSHKeyPassword are used for SSH URLs
+	SSHKeyFile     string
+	SSHKeyPassword string
+	// Context is used to log and record the operations against the running scenario
+	Context *utils.ScenarioContext
+}
+
+// NewGoGitBackend creates a GoGitBackend using the GitHub credentials, the SSH key in $BDD_GIT_SSH_KEY
+// (defaulting to ~/.ssh/id_rsa) with the optional $BDD_GIT_SSH_KEY_PASSWORD
+func NewGoGitBackend(c *utils.ScenarioContext) (*GoGitBackend, error) {
+	password, err := GetGitHubToken()
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		password = os.Getenv("GITHUB_PASSWORD")
+	}
+	utils.AddSecret(password)
+	keyFile := os.Getenv("BDD_GIT_SSH_KEY")
+	if keyFile == "" {
+		keyFile = filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa")
+	}
+	return &GoGitBackend{
+		Username:       os.Getenv("GITHUB_USER"),
+		Password:       password,
+		SSHKeyFile:     keyFile,
+		SSHKeyPassword: os.Getenv("BDD_GIT_SSH_KEY_PASSWORD"),
+		Context:        c,
+	}, nil
+}
+
+func (b *GoGitBackend) Clone(cloneURL string, dir string) error {
+	auth, err := b.authFor(cloneURL)
+	if err != nil {
+		return err
+	}
+	b.Context.LogInfof("cloning %s to %s\n", cloneURL, dir)
+	start := time.Now()
+	_, err = git.PlainClone(dir, false, &git.CloneOptions{
+		URL:  cloneURL,
+		Auth: auth,
+	})
+	b.recordGoGit(start, err, "clone", cloneURL, dir)
+	if err != nil {
+		return utils.RedactError(fmt.Errorf("Failed to clone %s to %s due to %v", cloneURL, dir, err))
+	}
+	return nil
+}
+
+func (b *GoGitBackend) AddRemote(dir string, name string, url string) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: name,
+		URLs: []string{url},
+	})
+	if err != nil {
+		return utils.RedactError(fmt.Errorf("Failed to add remote %s for %s in %s due to %v", name, url, dir, err))
+	}
+	return nil
+}
+
+func (b *GoGitBackend) Fetch(dir string, remote string) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	auth, err := b.remoteAuth(repo, remote)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: remote,
+		Auth:       auth,
+	})
+	b.recordGoGit(start, err, "fetch", remote)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return utils.RedactError(fmt.Errorf("Failed to fetch %s in %s due to %v", remote, dir, err))
+	}
+	return nil
+}
+
+func (b *GoGitBackend) Checkout(dir string, branch string) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	opts := &git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+	}
+	_, err = repo.Reference(opts.Branch, true)
+	if err == plumbing.ErrReferenceNotFound {
+		// lets create the local branch from the origin like `git checkout` does
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+		if err != nil {
+			return fmt.Errorf("Failed to find branch %s in %s due to %v", branch, dir, err)
+		}
+		opts.Create = true
+		opts.Hash = remoteRef.Hash()
+	} else if err != nil {
+		return err
+	}
+	err = w.Checkout(opts)
+	if err != nil {
+		return fmt.Errorf("Failed to checkout branch %s in %s due to %v", branch, dir, err)
+	}
+	return nil
+}
+
+func (b *GoGitBackend) CreateBranch(dir string, branch string) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	err = w.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+		Hash:   head.Hash(),
+		Create: true,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to create branch %s in %s due to %v", branch, dir, err)
+	}
+	return nil
+}
+
+func (b *GoGitBackend) ResetHard(dir string, rev string) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return fmt.Errorf("Failed to resolve %s in %s due to %v", rev, dir, err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	err = w.Reset(&git.ResetOptions{
+		Commit: *hash,
+		Mode:   git.HardReset,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to reset %s to %s due to %v", dir, rev, err)
+	}
+	return nil
+}
+
+func (b *GoGitBackend) CommitAll(dir string, message string, author *GitAuthor) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+	for path, s := range status {
+		if s.Worktree == git.Untracked {
+			_, err = w.Add(path)
+			if err != nil {
+				return fmt.Errorf("Failed to add %s in %s due to %v", path, dir, err)
+			}
+		}
+	}
+	_, err = w.Commit(message, &git.CommitOptions{
+		All:    true,
+		Author: signature(author),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to commit in %s due to %v", dir, err)
+	}
+	return nil
+}
+
+func (b *GoGitBackend) Tag(dir string, tag string, message string, author *GitAuthor) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	_, err = repo.CreateTag(tag, head.Hash(), &git.CreateTagOptions{
+		Tagger:  signature(author),
+		Message: message,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to create tag %s in %s due to %v", tag, dir, err)
+	}
+	return nil
+}
+
+func (b *GoGitBackend) Push(dir string, remote string, branch string, force bool) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	auth, err := b.remoteAuth(repo, remote)
+	if err != nil {
+		return err
+	}
+	refSpec := "refs/heads/" + branch + ":refs/heads/" + branch
+	if force {
+		refSpec = "+" + refSpec
+	}
+	start := time.Now()
+	err = repo.Push(&git.PushOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+		Auth:       auth,
+	})
+	b.recordGoGit(start, err, "push", remote, refSpec)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return utils.RedactError(fmt.Errorf("Failed to push %s to %s from %s due to %v", branch, remote, dir, err))
+	}
+	return nil
+}
+
+func (b *GoGitBackend) PushTag(dir string, remote string, tag string) error {
+	repo, err := b.open(dir)
+	if err != nil {
+		return err
+	}
+	auth, err := b.remoteAuth(repo, remote)
+	if err != nil {
+		return err
+	}
+	refSpec := "refs/tags/" + tag + ":refs/tags/" + tag
+	start := time.Now()
+	err = repo.Push(&git.PushOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+		Auth:       auth,
+	})
+	b.recordGoGit(start, err, "push", remote, refSpec)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return utils.RedactError(fmt.Errorf("Failed to push tag %s to %s from %s due to %v", tag, remote, dir, err))
+	}
+	return nil
+}
+
+func (b *GoGitBackend) RevParse(dir string, rev string) (string, error) {
+	repo, err := b.open(dir)
+	if err != nil {
+		return "", err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve %s in %s due to %v", rev, dir, err)
+	}
+	return hash.String(), nil
+}
+
+func (b *GoGitBackend) CheckRemoteBranch(dir string, cloneURL string, branch string) error {
+	auth, err := b.authFor(cloneURL)
+	if err != nil {
+		return err
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{cloneURL},
+	})
+	start := time.Now()
+	refs, err := remote.List(&git.ListOptions{
+		Auth: auth,
+	})
+	b.recordGoGit(start, err, "ls-remote", cloneURL, branch)
+	if err != nil {
+		return utils.RedactError(fmt.Errorf("Failed to list the references of %s due to %v", cloneURL, err))
+	}
+	name := plumbing.NewBranchReferenceName(branch)
+	for _, ref := range refs {
+		if ref.Name() == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("No branch %s found in %s", branch, cloneURL)
+}
+
+// signature returns the signature of the author defaulting to $GIT_AUTHOR_NAME and $GIT_AUTHOR_EMAIL
+func signature(author *GitAuthor) *object.Signature {
+	answer := &object.Signature{
+		Name:  os.Getenv("GIT_AUTHOR_NAME"),
+		Email: os.Getenv("GIT_AUTHOR_EMAIL"),
+		When:  time.Now(),
+	}
+	if author != nil {
+		if author.Name != "" {
+			answer.Name = author.Name
+		}
+		if author.Email != "" {
+			answer.Email = author.Email
+		}
+	}
+	if answer.Name == "" {
+		answer.Name = defaultGitAuthorName
+	}
+	return answer
+}
+
+// recordGoGit records the duration of the go-git operation in the event log
+func (b *GoGitBackend) recordGoGit(start time.Time, err error, args ...string) {
+	if err == git.NoErrAlreadyUpToDate {
+		err = nil
+	}
+	b.Context.RecordDuration(&utils.Event{
+		Type:    utils.EventGitCommand,
+		Command: utils.CommandLine("go-git", args...),
+	}, start, err)
+}
+
+func (b *GoGitBackend) open(dir string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open git repository %s due to %v", dir, err)
+	}
+	return repo, nil
+}
+
+// remoteAuth returns the credentials for the URL of the given remote
+func (b *GoGitBackend) remoteAuth(repo *git.Repository, name string) (transport.AuthMethod, error) {
+	remote, err := repo.Remote(name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find remote %s due to %v", name, err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	return b.authFor(urls[0])
+}
+
+// authFor returns the explicit credentials to use for the given URL
+func (b *GoGitBackend) authFor(url string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, utils.RedactError(fmt.Errorf("Invalid git URL %s due to %v", url, err))
+	}
+	switch endpoint.Protocol {
+	case "http", "https":
+		if b.Password == "" {
+			return nil, nil
+		}
+		username := b.Username
+		if username == "" {
+			username = "git"
+		}
+		return &http.BasicAuth{
+			Username: username,
+			Password: b.Password,
+		}, nil
+	case "ssh":
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		auth, err := ssh.NewPublicKeysFromFile(user, b.SSHKeyFile, b.SSHKeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load the SSH key %s for %s due to %v", b.SSHKeyFile, strings.TrimSpace(url), err)
+		}
+		return auth, nil
+	}
+	return nil, nil
+}
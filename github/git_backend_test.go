@@ -0,0 +1,187 @@
+package github
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+var testGitAuthor = &GitAuthor{
+	Name:  defaultGitAuthorName,
+	Email: "godog@example.com",
+}
+
+type gitBackendFeature struct {
+	Context  *utils.ScenarioContext
+	WorkDir  string
+	BareDir  string
+	CloneDir string
+	Backend  GitBackend
+}
+
+func (g *gitBackendFeature) aLocalBareGitRepositoryContaining(fileName string) error {
+	dir, err := ioutil.TempDir("", "godog-git-")
+	if err != nil {
+		return err
+	}
+	g.WorkDir = dir
+
+	// lets create the bare repository from a seed repository with a single commit
+	seedDir := filepath.Join(dir, "seed")
+	seed, err := git.PlainInit(seedDir, false)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(seedDir, fileName), []byte("initial content\n"), 0660)
+	if err != nil {
+		return err
+	}
+	w, err := seed.Worktree()
+	if err != nil {
+		return err
+	}
+	_, err = w.Add(fileName)
+	if err != nil {
+		return err
+	}
+	_, err = w.Commit("initial commit", &git.CommitOptions{
+		Author: signature(testGitAuthor),
+	})
+	if err != nil {
+		return err
+	}
+	g.BareDir = filepath.Join(dir, "bare.git")
+	_, err = git.PlainClone(g.BareDir, true, &git.CloneOptions{
+		URL: seedDir,
+	})
+	return err
+}
+
+func (g *gitBackendFeature) iCloneTheLocalRepositoryUsingTheGitBackend(name string) error {
+	var err error
+	g.Backend, err = CreateGitBackendNamed(g.Context, name)
+	if err != nil {
+		return err
+	}
+	err = g.Backend.CheckRemoteBranch(g.WorkDir, g.BareDir, "master")
+	if err != nil {
+		return err
+	}
+	g.CloneDir = filepath.Join(g.WorkDir, "clone")
+	return g.Backend.Clone(g.BareDir, g.CloneDir)
+}
+
+func (g *gitBackendFeature) iCommitAChangeToOnBranch(fileName string, branch string) error {
+	err := g.Backend.CreateBranch(g.CloneDir, branch)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(g.CloneDir, fileName), []byte("changed content\n"), 0660)
+	if err != nil {
+		return err
+	}
+	return g.Backend.CommitAll(g.CloneDir, "godog changing "+fileName, testGitAuthor)
+}
+
+func (g *gitBackendFeature) iPushBranchToTheLocalRepository(branch string) error {
+	return g.Backend.Push(g.CloneDir, "origin", branch, true)
+}
+
+func (g *gitBackendFeature) theLocalRepositoryBranchShouldHaveTheSameLastCommitAsTheClone(branch string) error {
+	bare, err := git.PlainOpen(g.BareDir)
+	if err != nil {
+		return err
+	}
+	hash, err := bare.ResolveRevision(plumbing.Revision("refs/heads/" + branch))
+	if err != nil {
+		return err
+	}
+	cloneSha, err := g.Backend.RevParse(g.CloneDir, "HEAD")
+	if err != nil {
+		return err
+	}
+	if hash.String() != cloneSha {
+		return fmt.Errorf("The branch %s has commit %s but the clone has commit %s", branch, hash.String(), cloneSha)
+	}
+	return nil
+}
+
+func (g *gitBackendFeature) iTagAndPushTheCloneWith(tag string) error {
+	err := g.Backend.Tag(g.CloneDir, tag, "godog tagging "+tag, testGitAuthor)
+	if err != nil {
+		return err
+	}
+	return g.Backend.PushTag(g.CloneDir, "origin", tag)
+}
+
+func (g *gitBackendFeature) theLocalRepositoryShouldHaveTagOnTheLastCommitOfTheClone(tag string) error {
+	bare, err := git.PlainOpen(g.BareDir)
+	if err != nil {
+		return err
+	}
+	hash, err := bare.ResolveRevision(plumbing.Revision("refs/tags/" + tag + "^{commit}"))
+	if err != nil {
+		return err
+	}
+	cloneSha, err := g.Backend.RevParse(g.CloneDir, "HEAD")
+	if err != nil {
+		return err
+	}
+	if hash.String() != cloneSha {
+		return fmt.Errorf("The tag %s points to commit %s but the clone has commit %s", tag, hash.String(), cloneSha)
+	}
+	return nil
+}
+
+func (g *gitBackendFeature) iResetTheCloneToBranch(branch string) error {
+	err := g.Backend.Checkout(g.CloneDir, "master")
+	if err != nil {
+		return err
+	}
+	err = g.Backend.Fetch(g.CloneDir, "origin")
+	if err != nil {
+		return err
+	}
+	return g.Backend.ResetHard(g.CloneDir, "origin/"+branch)
+}
+
+func (g *gitBackendFeature) theFileInTheCloneShouldContain(fileName string, expected string) error {
+	data, err := ioutil.ReadFile(filepath.Join(g.CloneDir, fileName))
+	if err != nil {
+		return err
+	}
+	if string(data) != expected+"\n" {
+		return fmt.Errorf("The file %s contains %s but expected %s", fileName, string(data), expected)
+	}
+	return nil
+}
+
+func GitBackendFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	g := &gitBackendFeature{
+		Context: c,
+	}
+
+	s.AfterScenario(func(interface{}, error) {
+		if g.WorkDir != "" {
+			os.RemoveAll(g.WorkDir)
+			g.WorkDir = ""
+		}
+	})
+
+	c.Step(`^a local bare git repository containing "([^"]*)"$`, g.aLocalBareGitRepositoryContaining)
+	c.Step(`^I clone the local repository using the "([^"]*)" git backend$`, g.iCloneTheLocalRepositoryUsingTheGitBackend)
+	c.Step(`^I commit a change to "([^"]*)" on branch "([^"]*)"$`, g.iCommitAChangeToOnBranch)
+	c.Step(`^I push branch "([^"]*)" to the local repository$`, g.iPushBranchToTheLocalRepository)
+	c.Step(`^the local repository branch "([^"]*)" should have the same last commit as the clone$`, g.theLocalRepositoryBranchShouldHaveTheSameLastCommitAsTheClone)
+	c.Step(`^I tag and push the clone with "([^"]*)"$`, g.iTagAndPushTheCloneWith)
+	c.Step(`^the local repository should have tag "([^"]*)" on the last commit of the clone$`, g.theLocalRepositoryShouldHaveTagOnTheLastCommitOfTheClone)
+	c.Step(`^I reset the clone to branch "([^"]*)"$`, g.iResetTheCloneToBranch)
+	c.Step(`^the file "([^"]*)" in the clone should contain "([^"]*)"$`, g.theFileInTheCloneShouldContain)
+}
@@ -3,10 +3,12 @@ package github
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/fabric8-jenkins/godog-jenkins/utils"
@@ -18,15 +20,22 @@ var stderrPrefix = utils.Color("\x1b[34m") + "        "
 type GitCommander struct {
 	Dir      string
 	UseHttps bool
+	Backend  GitBackend
+	Author   *GitAuthor
+	Context  *utils.ScenarioContext
 }
 
-func CreateGitCommander() *GitCommander {
-	dir := os.Getenv("WORK_DIR")
-	if len(dir) == 0 {
-		dir = "work"
+// CreateGitCommander creates a GitCommander using the work directory of the running scenario of the context
+// or $WORK_DIR if there is no running scenario
+func CreateGitCommander(c *utils.ScenarioContext) *GitCommander {
+	dir := utils.WorkDir()
+	if scenario := c.Scenario(); scenario != nil {
+		dir = scenario.WorkDir
 	}
 	return &GitCommander{
-		Dir: dir,
+		Dir:     dir,
+		Author:  CreateGitAuthor(),
+		Context: c,
 	}
 }
 
@@ -61,7 +70,11 @@ func (g *GitCommander) CloneFromURL(repo *github.Repository, cloneURL string) (s
 		return outDir, err
 	}
 
-	err = runCommand(runDir, "git", "clone", cloneURL)
+	backend, err := g.GetBackend()
+	if err != nil {
+		return outDir, err
+	}
+	err = backend.Clone(cloneURL, outDir)
 	return outDir, err
 }
 
@@ -70,12 +83,42 @@ func (g *GitCommander) GetCloneURL(repo *github.Repository) (string, error) {
 	return GetCloneURL(repo, g.UseHttps)
 }
 
+// GetBackend returns the GitBackend lazily creating it from the configuration if it has not been set
+func (g *GitCommander) GetBackend() (GitBackend, error) {
+	if g.Backend == nil {
+		backend, err := CreateGitBackend(g.Context)
+		if err != nil {
+			return nil, err
+		}
+		g.Backend = backend
+	}
+	return g.Backend, nil
+}
+
 func (commander *GitCommander) GetLastCommitSha(dir string) (string, error) {
-	text, err := commandAsString(dir, "git", "rev-parse", "HEAD")
+	backend, err := commander.GetBackend()
 	if err != nil {
-		return text, err
+		return "", err
 	}
-	return strings.TrimSpace(text), nil
+	return backend.RevParse(dir, "HEAD")
+}
+
+// WaitForCloneable waits for the branch of the given clone URL to be readable by git
+func (g *GitCommander) WaitForCloneable(cloneURL string, branch string, timeout time.Duration) error {
+	backend, err := g.GetBackend()
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	fn := func() (bool, error) {
+		lastErr = backend.CheckRemoteBranch(g.Dir, cloneURL, branch)
+		return lastErr == nil, nil
+	}
+	err = g.Context.PollPhase(utils.TimeoutForkReady, 2*time.Second, timeout, fmt.Sprintf("branch %s of %s to be cloneable", branch, cloneURL), fn)
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("%v: %v", err, lastErr)
+	}
+	return err
 }
 
 // DeleteWorkDir removes all files inside the work dir so that the test can start clean
@@ -105,55 +148,173 @@ func RemoveDirContents(dir string) error {
 	return nil
 }
 
+// ResetMasterFromUpstream resets the master branch of the repository in the directory to the upstream master
 func (commander *GitCommander) ResetMasterFromUpstream(dir string, upstreamCloneURL string) error {
-	err := runCommand(dir, "git", "remote", "add", "upstream", upstreamCloneURL)
+	return commander.ResetBranchFromUpstream(dir, upstreamCloneURL, "master")
+}
+
+// ResetBranchFromUpstream resets the branch of the repository in the directory to the same branch of the
+// upstream repository and force pushes it to the origin
+func (commander *GitCommander) ResetBranchFromUpstream(dir string, upstreamCloneURL string, branch string) error {
+	backend, err := commander.GetBackend()
 	if err != nil {
 		return err
 	}
-	err = runCommand(dir, "git", "fetch", "upstream")
+	err = backend.AddRemote(dir, "upstream", upstreamCloneURL)
 	if err != nil {
 		return err
 	}
-	err = runCommand(dir, "git", "checkout", "master")
+	err = backend.Fetch(dir, "upstream")
 	if err != nil {
 		return err
 	}
-	err = runCommand(dir, "git", "reset", "--hard", "upstream/master")
+	err = backend.Checkout(dir, branch)
 	if err != nil {
 		return err
 	}
-	err = runCommand(dir, "git", "push", "origin", "master", "--force")
+	err = backend.ResetHard(dir, "upstream/"+branch)
+	if err != nil {
+		return err
+	}
+	err = backend.Push(dir, "origin", branch, true)
 	if err == nil {
-		utils.LogInfof("reset the git repository at %s to the upstream master\n", dir)
+		commander.Context.LogInfof("reset the git repository at %s to the upstream %s\n", dir, branch)
 	}
 	return err
 }
 
-// runCommand runs the given command in the directory
-func runCommand(dir string, prog string, args ...string) error {
-	cmd := exec.Command(prog, args...)
-	cmd.Dir = dir
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = utils.NewPrefixWriter(os.Stdout, stdoutPrefix)
-	cmd.Stderr = utils.NewPrefixWriter(os.Stderr, stderrPrefix)
-	if err := cmd.Run(); err != nil {
+// CreateBranch creates a new local branch in the given directory and checks it out
+func (commander *GitCommander) CreateBranch(dir string, branch string) error {
+	backend, err := commander.GetBackend()
+	if err != nil {
+		return err
+	}
+	return backend.CreateBranch(dir, branch)
+}
+
+// CommitAll adds all of the changes in the given directory and commits them with the given message
+func (commander *GitCommander) CommitAll(dir string, message string) error {
+	backend, err := commander.GetBackend()
+	if err != nil {
+		return err
+	}
+	return backend.CommitAll(dir, message, commander.Author)
+}
+
+// Tag creates an annotated tag of HEAD in the given directory
+func (commander *GitCommander) Tag(dir string, tag string, message string) error {
+	backend, err := commander.GetBackend()
+	if err != nil {
+		return err
+	}
+	return backend.Tag(dir, tag, message, commander.Author)
+}
+
+// PushTag pushes the given tag to the origin remote
+func (commander *GitCommander) PushTag(dir string, tag string) error {
+	backend, err := commander.GetBackend()
+	if err != nil {
+		return err
+	}
+	err = backend.PushTag(dir, "origin", tag)
+	if err == nil {
+		commander.Context.LogInfof("pushed tag %s from %s\n", tag, dir)
+	}
+	return err
+}
+
+// WriteFile writes the content to the file at the given path relative to the directory creating any parent directories
+func (commander *GitCommander) WriteFile(dir string, path string, content string) error {
+	fileName := filepath.Join(dir, path)
+	err := os.MkdirAll(filepath.Dir(fileName), 0770)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(fileName, []byte(content), 0660)
+	if err != nil {
+		return fmt.Errorf("Failed to write file %s due to %v", fileName, err)
+	}
+	return nil
+}
+
+// Push force pushes the given branch to the origin remote
+func (commander *GitCommander) Push(dir string, branch string) error {
+	backend, err := commander.GetBackend()
+	if err != nil {
+		return err
+	}
+	err = backend.Push(dir, "origin", branch, true)
+	if err == nil {
+		commander.Context.LogInfof("pushed branch %s from %s\n", branch, dir)
+	}
+	return err
+}
+
+// runCommand runs the given command in the directory writing its output to the running scenario of the context
+func runCommand(c *utils.ScenarioContext, dir string, prog string, args ...string) error {
+	cmd := nonInteractiveCommand(dir, prog, args...)
+	stdout := utils.NewPrefixWriter(c.Output(), stdoutPrefix)
+	stderr := utils.NewPrefixWriter(c.ErrorOutput(), stderrPrefix)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := runAndRecord(c, cmd, prog, args)
+	stdout.Close()
+	stderr.Close()
+	if err != nil {
+		text := prog + " " + strings.Join(args, " ")
+		return utils.RedactError(fmt.Errorf("Failed to run command %s in dir %s due to error %v: %s", text, dir, err, strings.TrimSpace(stderr.FlushRecord())))
+	}
+	return nil
+}
+
+// runCommandQuietly runs the given command in the directory returning the output of the command in the
+// error if it fails
+func runCommandQuietly(c *utils.ScenarioContext, dir string, prog string, args ...string) error {
+	var outb bytes.Buffer
+	cmd := nonInteractiveCommand(dir, prog, args...)
+	cmd.Stdout = &outb
+	cmd.Stderr = &outb
+	if err := runAndRecord(c, cmd, prog, args); err != nil {
 		text := prog + " " + strings.Join(args, " ")
-		return fmt.Errorf("Failed to run command %s in dir %s due to error %v", text, dir, err)
+		return utils.RedactError(fmt.Errorf("Failed to run command %s in dir %s due to error %v: %s", text, dir, err, strings.TrimSpace(outb.String())))
 	}
 	return nil
 }
 
 // commandAsString runs the given command in the directory and returns the output of the command
-func commandAsString(dir string, prog string, args ...string) (string, error) {
+func commandAsString(c *utils.ScenarioContext, dir string, prog string, args ...string) (string, error) {
 	var outb bytes.Buffer
-	cmd := exec.Command(prog, args...)
-	cmd.Dir = dir
+	cmd := nonInteractiveCommand(dir, prog, args...)
 	cmd.Stdout = &outb
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	cmd.Stderr = c.ErrorOutput()
+	if err := runAndRecord(c, cmd, prog, args); err != nil {
 		text := prog + " " + strings.Join(args, " ")
-		return "", fmt.Errorf("Failed to run command %s in dir %s due to error %v", text, dir, err)
+		return "", utils.RedactError(fmt.Errorf("Failed to run command %s in dir %s due to error %v", text, dir, err))
 	}
 	return outb.String(), nil
 }
+
+// nonInteractiveCommand creates the command in the directory without any input and with the git terminal and
+// ssh prompts disabled, so a command needing credentials fails rather than hanging or reading the terminal
+// of the test run
+func nonInteractiveCommand(dir string, prog string, args ...string) *exec.Cmd {
+	cmd := exec.Command(prog, args...)
+	cmd.Dir = dir
+	cmd.Stdin = nil
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if os.Getenv("GIT_SSH_COMMAND") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	return cmd
+}
+
+// runAndRecord runs the command recording its duration in the event log
+func runAndRecord(c *utils.ScenarioContext, cmd *exec.Cmd, prog string, args []string) error {
+	start := time.Now()
+	err := cmd.Run()
+	c.RecordDuration(&utils.Event{
+		Type:    utils.EventGitCommand,
+		Command: utils.CommandLine(prog, args...),
+	}, start, err)
+	return err
+}
@@ -3,12 +3,26 @@ package github
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/fabric8-jenkins/godog-jenkins/utils"
 )
 
+const (
+	defaultBranch = "master"
+
+	// DefaultBranchVariable is the name of the variable containing the default branch of the forked repository
+	// which can be used in job expressions as ${DEFAULT_BRANCH}
+	DefaultBranchVariable = "DEFAULT_BRANCH"
+)
+
 type UserRepositoryName struct {
 	Organisation string
 	Repository   string
@@ -31,30 +45,131 @@ func ParseUserRepositoryName(text string) (*UserRepositoryName, error) {
 	}, nil
 }
 
-// CreateGitHubClient creates a new GitHub client
-func CreateGitHubClient() (*github.Client, error) {
-	/*
-		ctx := context.Background()
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: "... your access token ..."},
-		)
-		tc := oauth2.NewClient(ctx, ts)
-	*/
-
-	user, err := utils.MandatoryEnvVar("GITHUB_USER")
+// CreateGitHubClient creates a new GitHub client.
+//
+// Requests are authenticated with a token from $GITHUB_TOKEN, the file in $GITHUB_TOKEN_FILE or the git credential
+// helper if $GITHUB_CREDENTIAL_HELPER is true; otherwise $GITHUB_USER and $GITHUB_PASSWORD are used.
+// Set $GITHUB_URL and optionally $GITHUB_UPLOAD_URL to use a GitHub Enterprise server.
+// Requests are recorded against the running scenario of the context
+func CreateGitHubClient(c *utils.ScenarioContext) (*github.Client, error) {
+	token, err := GetGitHubToken()
 	if err != nil {
 		return nil, err
 	}
-	pwd, err := utils.MandatoryEnvVar("GITHUB_PASSWORD")
+	cassetteTransport := &utils.CassetteTransport{
+		Name:    "github",
+		Context: c,
+	}
+	var transport http.RoundTripper
+	if token != "" {
+		transport = &TokenTransport{
+			Token:     token,
+			Transport: cassetteTransport,
+		}
+	} else {
+		user, err := utils.MandatoryEnvVar("GITHUB_USER")
+		if err != nil {
+			return nil, err
+		}
+		pwd, err := utils.MandatoryEnvVar("GITHUB_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+		transport = &github.BasicAuthTransport{
+			Username:  user,
+			Password:  pwd,
+			Transport: cassetteTransport,
+		}
+	}
+	rateLimitTransport, err := NewRateLimitTransport(c, transport)
 	if err != nil {
 		return nil, err
 	}
-	basicAuth := github.BasicAuthTransport{
-		Username: user,
-		Password: pwd,
+	client := github.NewClient(&http.Client{Transport: rateLimitTransport})
+
+	baseURL := os.Getenv("GITHUB_URL")
+	if baseURL != "" {
+		client.BaseURL, err = parseAPIURL(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		uploadURL := os.Getenv("GITHUB_UPLOAD_URL")
+		if uploadURL == "" {
+			uploadURL = strings.Replace(client.BaseURL.String(), "/api/v3/", "/api/uploads/", 1)
+		}
+		client.UploadURL, err = parseAPIURL(uploadURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// GetGitHubToken returns the GitHub access token from $GITHUB_TOKEN, the file in $GITHUB_TOKEN_FILE
+// or the git credential helper if $GITHUB_CREDENTIAL_HELPER is true. Returns an empty string if no token is configured
+func GetGitHubToken() (string, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token != "" {
+		return token, nil
+	}
+	tokenFile := os.Getenv("GITHUB_TOKEN_FILE")
+	if tokenFile != "" {
+		data, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read the GitHub token file %s due to %v", tokenFile, err)
+		}
+		token = strings.TrimSpace(string(data))
+		utils.AddSecret(token)
+		return token, nil
+	}
+	if os.Getenv("GITHUB_CREDENTIAL_HELPER") == "true" {
+		return getCredentialHelperToken()
 	}
-	httpClient := basicAuth.Client()
-	return github.NewClient(httpClient), nil
+	return "", nil
+}
+
+// getCredentialHelperToken asks the git credential helper for the password of the GitHub host
+func getCredentialHelperToken() (string, error) {
+	host := "github.com"
+	baseURL := os.Getenv("GITHUB_URL")
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return "", fmt.Errorf("Invalid $GITHUB_URL %s due to %v", baseURL, err)
+		}
+		host = u.Host
+	}
+	input := "protocol=https\nhost=" + host + "\n"
+	user := os.Getenv("GITHUB_USER")
+	if user != "" {
+		input += "username=" + user + "\n"
+	}
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input + "\n")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Failed to get the GitHub token for %s from the git credential helper due to %v", host, err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "password=") {
+			token := strings.TrimPrefix(line, "password=")
+			utils.AddSecret(token)
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("The git credential helper has no password for %s", host)
+}
+
+func parseAPIURL(text string) (*url.URL, error) {
+	if !strings.HasSuffix(text, "/") {
+		text += "/"
+	}
+	u, err := url.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid GitHub URL %s due to %v", text, err)
+	}
+	return u, nil
 }
 
 func GetRepository(client *github.Client, owner string, name string) (*github.Repository, error) {
@@ -63,9 +178,18 @@ func GetRepository(client *github.Client, owner string, name string) (*github.Re
 	return repo, err
 }
 
+// GetDefaultBranch returns the default branch of the repository defaulting to master
+func GetDefaultBranch(repo *github.Repository) string {
+	branch := repo.GetDefaultBranch()
+	if branch == "" {
+		return defaultBranch
+	}
+	return branch
+}
+
 // ForkRepositoryOrRevertMasterInFork forks the given repository to the new owner or resets the fork
 // to the upstream master
-func ForkRepositoryOrRevertMasterInFork(client *github.Client, userRepo *UserRepositoryName, newOwner string) (*github.Repository, error) {
+func ForkRepositoryOrRevertMasterInFork(c *utils.ScenarioContext, client *github.Client, userRepo *UserRepositoryName, newOwner string) (*github.Repository, error) {
 	repoOwner := userRepo.Organisation
 	repoName := userRepo.Repository
 	repo, err := GetRepository(client, repoOwner, repoName)
@@ -74,20 +198,16 @@ func ForkRepositoryOrRevertMasterInFork(client *github.Client, userRepo *UserRep
 	}
 	u := repo.HTMLURL
 	if u != nil {
-		utils.LogInfof("Found repository at %s\n", *u)
+		c.LogInfof("Found repository at %s\n", *u)
 	}
 
 	forkRepo, err := GetRepository(client, newOwner, repoName)
-
-	// TODO how to ignore just 404 errors?
-	/*
-	if err != nil {
-		return nil, fmt.Errorf("Error checking if the fork already exists for %s/%s due to %#v", newOwner, repoName, err)
+	if err != nil && !IsNotFound(err) {
+		return nil, fmt.Errorf("Error checking if the fork already exists for %s/%s due to %v", newOwner, repoName, err)
 	}
-	*/
 
 	if forkRepo == nil || err != nil {
-		utils.LogInfof("No fork available yet for %s/%s\n", newOwner, repoName)
+		c.LogInfof("No fork available yet for %s/%s\n", newOwner, repoName)
 
 		isUser, err := IsUser(client, repoOwner)
 		if err != nil {
@@ -98,14 +218,67 @@ func ForkRepositoryOrRevertMasterInFork(client *github.Client, userRepo *UserRep
 			opts.Organization = newOwner
 		}
 		ctx := context.Background()
-		forkRepo, _, err = client.Repositories.CreateFork(ctx, repoOwner, repoName, opts)
-		if err != nil {
+		_, _, err = client.Repositories.CreateFork(ctx, repoOwner, repoName, opts)
+		if _, accepted := err.(*github.AcceptedError); err != nil && !accepted {
 			return nil, fmt.Errorf("Failed to fork repo %s to user %s due to %s", userRepo.String(), newOwner, err)
 		}
+
+		// GitHub creates forks asynchronously so lets wait for it to be available
+		forkRepo, err = WaitForRepositoryToBeReady(c, client, &UserRepositoryName{newOwner, repoName}, ForkReadyTimeout(c))
+		if err != nil {
+			return nil, err
+		}
 	}
 	return forkRepo, nil
 }
 
+// ForkReadyTimeout returns the maximum time to wait for a new fork to be ready from a `@timeout-fork-ready` tag,
+// $BDD_FORK_READY_TIMEOUT or a default of 5 minutes
+func ForkReadyTimeout(c *utils.ScenarioContext) time.Duration {
+	return c.Timeout(utils.TimeoutForkReady)
+}
+
+// WaitForRepositoryToBeReady waits for the repository to exist and for its default branch to be present
+func WaitForRepositoryToBeReady(c *utils.ScenarioContext, client *github.Client, userRepo *UserRepositoryName, timeout time.Duration) (*github.Repository, error) {
+	var repo *github.Repository
+	fn := func() (bool, error) {
+		r, err := GetRepository(client, userRepo.Organisation, userRepo.Repository)
+		if err != nil {
+			if IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("Failed to find repository %s due to %v", userRepo.String(), err)
+		}
+		branch := r.GetDefaultBranch()
+		if branch == "" {
+			return false, nil
+		}
+		ctx := context.Background()
+		_, _, err = client.Repositories.GetBranch(ctx, userRepo.Organisation, userRepo.Repository, branch)
+		if err != nil {
+			if IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("Failed to find branch %s of repository %s due to %v", branch, userRepo.String(), err)
+		}
+		repo = r
+		return true, nil
+	}
+	err := c.PollPhase(utils.TimeoutForkReady, 2*time.Second, timeout, fmt.Sprintf("repository %s to be ready", userRepo.String()), fn)
+	if err == nil {
+		c.LogInfof("repository %s is ready\n", userRepo.String())
+	}
+	return repo, err
+}
+
+// IsNotFound returns true if the error is a 404 response from GitHub
+func IsNotFound(err error) bool {
+	if errorResponse, ok := err.(*github.ErrorResponse); ok {
+		return errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
 // IsUser returns true if this name is a User or false if its an Organisation
 func IsUser(client *github.Client, name string) (bool, error) {
 	ctx := context.Background()
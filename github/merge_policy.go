@@ -0,0 +1,297 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/google/go-github/github"
+)
+
+const (
+	defaultMergeMethod        = "rebase"
+	defaultMergeCommitMessage = "godog merging"
+)
+
+// MergePolicy decides which pull requests get merged and how they are merged
+type MergePolicy struct {
+	// Authors are the logins of the users whose pull requests may be merged. Any author is allowed if empty
+	Authors []string
+	// TitleRegex if specified must match the title of the pull request
+	TitleRegex *regexp.Regexp
+	// BranchRegex if specified must match the head branch of the pull request
+	BranchRegex *regexp.Regexp
+	// ExpectedFiles are the file names that the pull request must change
+	ExpectedFiles []string
+	// MergeMethod is one of merge, squash or rebase
+	MergeMethod string
+	// CommitMessage is the message used for the merge commit
+	CommitMessage string
+	// WaitForStatusChecks waits for the status checks of the pull request to pass before merging
+	WaitForStatusChecks bool
+	// StatusCheckTimeout is the maximum time to wait for status checks to pass
+	StatusCheckTimeout time.Duration
+	// Validate if set checks a pull request before it is merged so that a broken pull request fails fast
+	Validate func(client *github.Client, pr *github.PullRequest) error
+	// Context is the scenario context that merges are logged to
+	Context *utils.ScenarioContext
+}
+
+// MergedPullRequest records a pull request which was merged by a MergePolicy
+type MergedPullRequest struct {
+	Number int
+	URL    string
+	Title  string
+	Author string
+	Branch string
+	SHA    string
+}
+
+func (m *MergedPullRequest) String() string {
+	return fmt.Sprintf("#%d %s by %s from branch %s: %s", m.Number, m.URL, m.Author, m.Branch, m.Title)
+}
+
+// CreateMergePolicy creates the merge policy from the environment variables
+// BDD_MERGE_AUTHORS, BDD_MERGE_TITLE_REGEX, BDD_MERGE_BRANCH_REGEX, BDD_MERGE_FILES,
+// BDD_MERGE_METHOD, BDD_MERGE_COMMIT_MESSAGE and BDD_MERGE_WAIT_FOR_STATUS
+func CreateMergePolicy(c *utils.ScenarioContext) (*MergePolicy, error) {
+	policy := &MergePolicy{
+		Authors:             SplitList(os.Getenv("BDD_MERGE_AUTHORS")),
+		ExpectedFiles:       SplitList(os.Getenv("BDD_MERGE_FILES")),
+		MergeMethod:         defaultMergeMethod,
+		CommitMessage:       defaultMergeCommitMessage,
+		WaitForStatusChecks: os.Getenv("BDD_MERGE_WAIT_FOR_STATUS") == "true",
+		StatusCheckTimeout:  c.Timeout(utils.TimeoutStatusCheck),
+		Context:             c,
+	}
+	err := policy.SetTitleRegex(os.Getenv("BDD_MERGE_TITLE_REGEX"))
+	if err != nil {
+		return nil, err
+	}
+	err = policy.SetBranchRegex(os.Getenv("BDD_MERGE_BRANCH_REGEX"))
+	if err != nil {
+		return nil, err
+	}
+	method := os.Getenv("BDD_MERGE_METHOD")
+	if method != "" {
+		err = policy.SetMergeMethod(method)
+		if err != nil {
+			return nil, err
+		}
+	}
+	message := os.Getenv("BDD_MERGE_COMMIT_MESSAGE")
+	if message != "" {
+		policy.CommitMessage = message
+	}
+	return policy, nil
+}
+
+// SetTitleRegex sets the regular expression which titles of merged pull requests must match
+func (p *MergePolicy) SetTitleRegex(expression string) error {
+	r, err := compileOptionalRegex(expression)
+	if err != nil {
+		return fmt.Errorf("Invalid pull request title regex %s due to %v", expression, err)
+	}
+	p.TitleRegex = r
+	return nil
+}
+
+// SetBranchRegex sets the regular expression which branches of merged pull requests must match
+func (p *MergePolicy) SetBranchRegex(expression string) error {
+	r, err := compileOptionalRegex(expression)
+	if err != nil {
+		return fmt.Errorf("Invalid pull request branch regex %s due to %v", expression, err)
+	}
+	p.BranchRegex = r
+	return nil
+}
+
+// SetMergeMethod sets the merge method which must be one of merge, squash or rebase
+func (p *MergePolicy) SetMergeMethod(method string) error {
+	switch method {
+	case "merge", "squash", "rebase":
+		p.MergeMethod = method
+		return nil
+	}
+	return fmt.Errorf("Invalid merge method %s. Expected merge, squash or rebase", method)
+}
+
+// Matches returns true if the author, title and branch of the pull request allow this policy to merge it or
+// the reason why the pull request is ignored
+func (p *MergePolicy) Matches(pr *github.PullRequest) (bool, string) {
+	author := pullRequestAuthor(pr)
+	if len(p.Authors) > 0 && !containsString(p.Authors, author) {
+		return false, fmt.Sprintf("author %s is not one of %s", author, strings.Join(p.Authors, ", "))
+	}
+	title := ""
+	if pr.Title != nil {
+		title = *pr.Title
+	}
+	if p.TitleRegex != nil && !p.TitleRegex.MatchString(title) {
+		return false, fmt.Sprintf("title %s does not match %s", title, p.TitleRegex.String())
+	}
+	branch := pullRequestBranch(pr)
+	if p.BranchRegex != nil && !p.BranchRegex.MatchString(branch) {
+		return false, fmt.Sprintf("branch %s does not match %s", branch, p.BranchRegex.String())
+	}
+	return true, ""
+}
+
+// ChangesExpectedFiles returns true if the pull request changes all of the expected files of this policy or the
+// reason why it does not. GitHub lists the files of a new pull request asynchronously so a pull request which
+// does not change them yet should be checked again later rather than ignored
+func (p *MergePolicy) ChangesExpectedFiles(client *github.Client, userRepo *UserRepositoryName, pr *github.PullRequest) (bool, string, error) {
+	if len(p.ExpectedFiles) == 0 {
+		return true, "", nil
+	}
+	changed, err := listChangedFiles(client, userRepo, *pr.Number)
+	if err != nil {
+		return false, "", err
+	}
+	for _, expected := range p.ExpectedFiles {
+		if !containsString(changed, expected) {
+			return false, fmt.Sprintf("it does not change %s yet", expected), nil
+		}
+	}
+	return true, "", nil
+}
+
+// Merge merges the pull request using the merge method of this policy, waiting for status checks first if required
+func (p *MergePolicy) Merge(client *github.Client, userRepo *UserRepositoryName, pr *github.PullRequest) (*MergedPullRequest, error) {
+	merged := &MergedPullRequest{
+		Number: *pr.Number,
+		Author: pullRequestAuthor(pr),
+		Branch: pullRequestBranch(pr),
+	}
+	if pr.HTMLURL != nil {
+		merged.URL = *pr.HTMLURL
+	}
+	if pr.Title != nil {
+		merged.Title = *pr.Title
+	}
+	sha := ""
+	if pr.Head != nil && pr.Head.SHA != nil {
+		sha = *pr.Head.SHA
+	}
+	if p.Validate != nil {
+		err := p.Validate(client, pr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to merge PR %s due to %v", merged.URL, err)
+		}
+	}
+	if p.WaitForStatusChecks && sha != "" {
+		err := waitForStatusChecksToPass(p.Context, client, userRepo, sha, p.StatusCheckTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to merge PR %s due to %v", merged.URL, err)
+		}
+	}
+
+	ctx := context.Background()
+	mergeOpts := &github.PullRequestOptions{
+		MergeMethod: p.MergeMethod,
+		SHA:         sha,
+	}
+	r, _, err := client.PullRequests.Merge(ctx, userRepo.Organisation, userRepo.Repository, merged.Number, p.CommitMessage, mergeOpts)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to merge PR %s due to %v", merged.URL, err)
+	}
+	if r.Merged == nil || !*r.Merged {
+		return nil, fmt.Errorf("Failed to merge PR %s got result %v", merged.URL, r)
+	}
+	if r.SHA != nil {
+		merged.SHA = *r.SHA
+	}
+	p.Context.LogInfof("merged PR %s %s using %s\n", merged.URL, merged.Title, p.MergeMethod)
+	return merged, nil
+}
+
+// waitForStatusChecksToPass waits for the combined commit status of the given ref to no longer be pending
+func waitForStatusChecksToPass(c *utils.ScenarioContext, client *github.Client, userRepo *UserRepositoryName, ref string, timeout time.Duration) error {
+	fn := func() (bool, error) {
+		combined, err := GetCombinedStatus(client, userRepo, ref)
+		if err != nil {
+			return false, err
+		}
+		if combined.TotalCount == nil || *combined.TotalCount == 0 || combined.State == nil {
+			return true, nil
+		}
+		switch *combined.State {
+		case "success":
+			return true, nil
+		case "pending":
+			return false, nil
+		}
+		return false, fmt.Errorf("the status checks of commit %s on %s have state %s", ref, userRepo.String(), *combined.State)
+	}
+	return c.PollPhase(utils.TimeoutStatusCheck, 5*time.Second, timeout, fmt.Sprintf("status checks of commit %s on %s to pass", ref, userRepo.String()), fn)
+}
+
+func pullRequestAuthor(pr *github.PullRequest) string {
+	if pr.User != nil && pr.User.Login != nil {
+		return *pr.User.Login
+	}
+	return ""
+}
+
+func pullRequestBranch(pr *github.PullRequest) string {
+	if pr.Head != nil && pr.Head.Ref != nil {
+		return *pr.Head.Ref
+	}
+	return ""
+}
+
+// listChangedFiles returns the names of every file the pull request changes reading all of the pages of them
+func listChangedFiles(client *github.Client, userRepo *UserRepositoryName, number int) ([]string, error) {
+	ctx := context.Background()
+	opts := &github.ListOptions{
+		PerPage: 100,
+	}
+	changed := []string{}
+	for {
+		files, resp, err := client.PullRequests.ListFiles(ctx, userRepo.Organisation, userRepo.Repository, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list the files of PR #%d on %s due to %v", number, userRepo.String(), err)
+		}
+		for _, file := range files {
+			if file.Filename != nil {
+				changed = append(changed, *file.Filename)
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return changed, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func compileOptionalRegex(expression string) (*regexp.Regexp, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expression)
+}
+
+// SplitList splits a comma separated list ignoring blank values
+func SplitList(text string) []string {
+	answer := []string{}
+	for _, value := range strings.Split(text, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			answer = append(answer, value)
+		}
+	}
+	return answer
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
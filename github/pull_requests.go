@@ -0,0 +1,61 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/google/go-github/github"
+)
+
+// CreatePullRequest opens a pull request on the given repository from the head branch into the base branch.
+// The head can be of the form `owner:branch` to create a pull request from a fork
+func CreatePullRequest(c *utils.ScenarioContext, client *github.Client, userRepo *UserRepositoryName, title string, head string, base string) (*github.PullRequest, error) {
+	ctx := context.Background()
+	body := "created by godog-jenkins"
+	newPR := &github.NewPullRequest{
+		Title: &title,
+		Head:  &head,
+		Base:  &base,
+		Body:  &body,
+	}
+	pr, _, err := client.PullRequests.Create(ctx, userRepo.Organisation, userRepo.Repository, newPR)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create PullRequest on %s from %s to %s due to %v", userRepo.String(), head, base, err)
+	}
+	if pr.HTMLURL != nil {
+		c.LogInfof("created PR %s\n", *pr.HTMLURL)
+	}
+	return pr, nil
+}
+
+// GetPullRequestFile returns the content of the file in the head commit of the pull request or false if the
+// pull request does not contain the file
+func GetPullRequestFile(client *github.Client, pr *github.PullRequest, path string) (string, bool, error) {
+	if pr.Head == nil || pr.Head.Repo == nil || pr.Head.Repo.FullName == nil || pr.Head.SHA == nil {
+		return "", false, fmt.Errorf("No head commit for PR #%d", pr.GetNumber())
+	}
+	userRepo, err := ParseUserRepositoryName(*pr.Head.Repo.FullName)
+	if err != nil {
+		return "", false, err
+	}
+	ctx := context.Background()
+	opts := &github.RepositoryContentGetOptions{
+		Ref: *pr.Head.SHA,
+	}
+	file, _, _, err := client.Repositories.GetContents(ctx, userRepo.Organisation, userRepo.Repository, path, opts)
+	if err != nil {
+		if IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("Failed to get %s of PR #%d on %s due to %v", path, pr.GetNumber(), userRepo.String(), err)
+	}
+	if file == nil {
+		return "", false, fmt.Errorf("%s of PR #%d on %s is a directory", path, pr.GetNumber(), userRepo.String())
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return "", false, fmt.Errorf("Failed to decode %s of PR #%d on %s due to %v", path, pr.GetNumber(), userRepo.String(), err)
+	}
+	return content, true, nil
+}
@@ -0,0 +1,216 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+const (
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+
+	defaultRateLimitMaxWait = 5 * time.Minute
+
+	// the shortest wait before retrying a rejected request in case the reset time has already passed
+	minRateLimitBackoff = 1 * time.Second
+)
+
+// TokenTransport is a http.RoundTripper which authenticates requests using a GitHub access token
+type TokenTransport struct {
+	Token     string
+	Transport http.RoundTripper
+}
+
+// RoundTrip adds the token to a copy of the request
+func (t *TokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := cloneRequest(req)
+	r.Header.Set("Authorization", "token "+t.Token)
+	return transportOrDefault(t.Transport).RoundTrip(r)
+}
+
+// RateLimit is the last known GitHub API rate limit
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitTransport is a http.RoundTripper which tracks the GitHub rate limit headers.
+// When the quota is exhausted it waits for the quota to reset if that happens within MaxWait
+// otherwise it fails fast with a clear message rather than making requests that GitHub will reject
+type RateLimitTransport struct {
+	MaxWait   time.Duration
+	Context   *utils.ScenarioContext
+	Transport http.RoundTripper
+}
+
+var (
+	rateLimitLock sync.Mutex
+	lastRateLimit *RateLimit
+)
+
+// NewRateLimitTransport creates a RateLimitTransport using the maximum wait in $BDD_GITHUB_RATE_LIMIT_MAX_WAIT
+// which logs and records requests against the running scenario of the context
+func NewRateLimitTransport(c *utils.ScenarioContext, transport http.RoundTripper) (*RateLimitTransport, error) {
+	maxWait := defaultRateLimitMaxWait
+	text := os.Getenv("BDD_GITHUB_RATE_LIMIT_MAX_WAIT")
+	if text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid duration $BDD_GITHUB_RATE_LIMIT_MAX_WAIT %s due to %v", text, err)
+		}
+		maxWait = d
+	}
+	return &RateLimitTransport{
+		MaxWait:   maxWait,
+		Context:   c,
+		Transport: transport,
+	}, nil
+}
+
+// RoundTrip waits for or fails on an exhausted rate limit before performing the request and records
+// the rate limit of the response. A request without a body which GitHub rejects because the quota is
+// exhausted is retried once after the quota resets
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.roundTrip(req)
+	if err != nil || !isRateLimited(resp) || req.Body != nil {
+		return resp, err
+	}
+	resp.Body.Close()
+	err = t.waitForReset()
+	if err != nil {
+		return nil, err
+	}
+	return t.roundTrip(req)
+}
+
+func (t *RateLimitTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	err := t.waitForQuota()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := transportOrDefault(t.Transport).RoundTrip(req)
+	if err != nil {
+		t.Context.RecordDuration(&utils.Event{
+			Type:   utils.EventGitHubRequest,
+			Method: req.Method,
+			Path:   req.URL.Path,
+		}, start, err)
+		return resp, err
+	}
+	rate := parseRateLimit(resp)
+	event := &utils.Event{
+		Type:   utils.EventGitHubRequest,
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Status: resp.StatusCode,
+	}
+	if rate != nil {
+		event.RateLimitRemaining = &rate.Remaining
+	}
+	t.Context.RecordDuration(event, start, nil)
+	if rate != nil {
+		rateLimitLock.Lock()
+		lastRateLimit = rate
+		rateLimitLock.Unlock()
+	}
+	return resp, nil
+}
+
+// isRateLimited returns true if GitHub rejected the request because the quota is exhausted
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	rate := parseRateLimit(resp)
+	return rate != nil && rate.Remaining == 0
+}
+
+// waitForReset waits for the exhausted quota to reset, waiting at least minRateLimitBackoff so that a
+// reset time which has already passed does not retry straight away
+func (t *RateLimitTransport) waitForReset() error {
+	rate := GetRateLimit()
+	if rate != nil && rate.Reset.Sub(time.Now()) > minRateLimitBackoff {
+		return t.waitForQuota()
+	}
+	utils.Sleep(minRateLimitBackoff)
+	return nil
+}
+
+func (t *RateLimitTransport) waitForQuota() error {
+	rate := GetRateLimit()
+	if rate == nil || rate.Remaining > 0 {
+		return nil
+	}
+	wait := rate.Reset.Sub(time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	if wait > t.MaxWait {
+		return fmt.Errorf("GitHub API rate limit of %d requests is exhausted and does not reset until %s which is in %s. Set $BDD_GITHUB_RATE_LIMIT_MAX_WAIT to wait longer",
+			rate.Limit, rate.Reset.Format(time.RFC3339), wait.String())
+	}
+	t.Context.LogInfof("GitHub API rate limit of %d requests is exhausted, waiting %s for it to reset\n", rate.Limit, wait.String())
+	utils.Sleep(wait)
+	return nil
+}
+
+// GetRateLimit returns the last known GitHub rate limit or nil if no request has been made yet
+func GetRateLimit() *RateLimit {
+	rateLimitLock.Lock()
+	defer rateLimitLock.Unlock()
+	if lastRateLimit == nil {
+		return nil
+	}
+	answer := *lastRateLimit
+	return &answer
+}
+
+// LogRateLimit logs the remaining GitHub API quota to the running scenario if any requests have been made
+func LogRateLimit(c *utils.ScenarioContext) {
+	rate := GetRateLimit()
+	if rate != nil {
+		c.LogInfof("GitHub API rate limit: %d of %d requests remaining, resets at %s\n", rate.Remaining, rate.Limit, rate.Reset.Format(time.RFC3339))
+	}
+}
+
+func parseRateLimit(resp *http.Response) *RateLimit {
+	remaining := resp.Header.Get(headerRateRemaining)
+	if remaining == "" {
+		return nil
+	}
+	rate := &RateLimit{}
+	rate.Remaining, _ = strconv.Atoi(remaining)
+	rate.Limit, _ = strconv.Atoi(resp.Header.Get(headerRateLimit))
+	reset, err := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64)
+	if err == nil {
+		rate.Reset = time.Unix(reset, 0)
+	}
+	return rate
+}
+
+func transportOrDefault(transport http.RoundTripper) http.RoundTripper {
+	if transport != nil {
+		return transport
+	}
+	return http.DefaultTransport
+}
+
+// cloneRequest returns a shallow copy of the request with a copy of its headers
+func cloneRequest(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	return r
+}
@@ -0,0 +1,238 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	// WebhookEventPush is the GitHub event sent when commits are pushed to a branch
+	WebhookEventPush = "push"
+	// WebhookEventPullRequest is the GitHub event sent when a pull request is opened or changed
+	WebhookEventPullRequest = "pull_request"
+
+	// the SHA GitHub uses for the before commit of a new branch
+	zeroSha = "0000000000000000000000000000000000000000"
+)
+
+// Webhook is a webhook request as GitHub would send it
+type Webhook struct {
+	Event    string
+	Delivery string
+	Payload  []byte
+}
+
+// webhookRepository is the repository in a push payload which has a different owner to the REST API
+type webhookRepository struct {
+	ID            int           `json:"id,omitempty"`
+	Name          string        `json:"name"`
+	FullName      string        `json:"full_name"`
+	Owner         *webhookOwner `json:"owner"`
+	Private       bool          `json:"private"`
+	Fork          bool          `json:"fork"`
+	HTMLURL       string        `json:"html_url"`
+	URL           string        `json:"url"`
+	GitURL        string        `json:"git_url"`
+	SSHURL        string        `json:"ssh_url"`
+	CloneURL      string        `json:"clone_url"`
+	DefaultBranch string        `json:"default_branch"`
+	MasterBranch  string        `json:"master_branch"`
+}
+
+type webhookOwner struct {
+	Name  string `json:"name"`
+	Login string `json:"login"`
+	Email string `json:"email,omitempty"`
+}
+
+type webhookCommit struct {
+	ID        string        `json:"id"`
+	TreeID    string        `json:"tree_id,omitempty"`
+	Distinct  bool          `json:"distinct"`
+	Message   string        `json:"message"`
+	Timestamp string        `json:"timestamp"`
+	URL       string        `json:"url"`
+	Author    *webhookOwner `json:"author"`
+	Committer *webhookOwner `json:"committer"`
+	Added     []string      `json:"added"`
+	Removed   []string      `json:"removed"`
+	Modified  []string      `json:"modified"`
+}
+
+type pushPayload struct {
+	Ref        string             `json:"ref"`
+	Before     string             `json:"before"`
+	After      string             `json:"after"`
+	Created    bool               `json:"created"`
+	Deleted    bool               `json:"deleted"`
+	Forced     bool               `json:"forced"`
+	BaseRef    *string            `json:"base_ref"`
+	Compare    string             `json:"compare"`
+	Commits    []*webhookCommit   `json:"commits"`
+	HeadCommit *webhookCommit     `json:"head_commit"`
+	Repository *webhookRepository `json:"repository"`
+	Pusher     *webhookOwner      `json:"pusher"`
+	Sender     *github.User       `json:"sender"`
+}
+
+// WebhookSecret returns the secret used to sign webhooks from $BDD_GITHUB_WEBHOOK_SECRET or an empty
+// string if webhooks should not be signed
+func WebhookSecret() string {
+	return os.Getenv("BDD_GITHUB_WEBHOOK_SECRET")
+}
+
+// PushWebhook returns the webhook GitHub sends when the branch of the repository is pushed moving it
+// from the before to the after commit. An empty before commit means the branch was created
+func PushWebhook(repo *github.Repository, branch string, before string, after string) (*Webhook, error) {
+	if after == "" {
+		return nil, fmt.Errorf("No commit SHA for the push webhook of branch %s", branch)
+	}
+	if repo.Owner == nil {
+		return nil, fmt.Errorf("No owner for repository %s", repo.GetFullName())
+	}
+	owner := &webhookOwner{
+		Name:  repo.Owner.GetLogin(),
+		Login: repo.Owner.GetLogin(),
+	}
+	payload := &pushPayload{
+		Ref:        "refs/heads/" + branch,
+		Before:     before,
+		After:      after,
+		Repository: toWebhookRepository(repo, owner),
+		Pusher:     owner,
+		Sender:     repo.Owner,
+	}
+	if before == "" {
+		payload.Before = zeroSha
+		payload.Created = true
+		payload.Compare = fmt.Sprintf("%s/commit/%s", repo.GetHTMLURL(), shortSha(after))
+	} else {
+		payload.Compare = fmt.Sprintf("%s/compare/%s...%s", repo.GetHTMLURL(), shortSha(before), shortSha(after))
+	}
+	commit := &webhookCommit{
+		ID:        after,
+		Distinct:  true,
+		Message:   "godog push of " + branch,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		URL:       fmt.Sprintf("%s/commit/%s", repo.GetHTMLURL(), after),
+		Author:    owner,
+		Committer: owner,
+		Added:     []string{},
+		Removed:   []string{},
+		Modified:  []string{},
+	}
+	payload.Commits = []*webhookCommit{commit}
+	payload.HeadCommit = commit
+	return newWebhook(WebhookEventPush, payload)
+}
+
+// PullRequestWebhook returns the webhook GitHub sends for the action such as opened or synchronize on the
+// pull request. The head SHA of the pull request is replaced by the given SHA if it is not empty
+func PullRequestWebhook(pr *github.PullRequest, action string, headSha string) (*Webhook, error) {
+	if pr == nil || pr.Base == nil || pr.Base.Repo == nil || pr.Head == nil {
+		return nil, fmt.Errorf("No pull request to send a webhook for")
+	}
+	copy := *pr
+	if headSha != "" {
+		head := *pr.Head
+		head.SHA = &headSha
+		copy.Head = &head
+	}
+	payload := &github.PullRequestEvent{
+		Action:      &action,
+		Number:      pr.Number,
+		PullRequest: &copy,
+		Repo:        pr.Base.Repo,
+		Sender:      pr.User,
+	}
+	return newWebhook(WebhookEventPullRequest, payload)
+}
+
+// Headers returns the headers GitHub sends with the webhook signing the payload if the secret is not empty
+func (w *Webhook) Headers(secret string) http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("User-Agent", "GitHub-Hookshot/godog")
+	header.Set("X-GitHub-Event", w.Event)
+	header.Set("X-GitHub-Delivery", w.Delivery)
+	if secret != "" {
+		header.Set("X-Hub-Signature", "sha1="+signPayload(sha1.New, secret, w.Payload))
+		header.Set("X-Hub-Signature-256", "sha256="+signPayload(sha256.New, secret, w.Payload))
+	}
+	return header
+}
+
+func signPayload(fn func() hash.Hash, secret string, payload []byte) string {
+	mac := hmac.New(fn, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhook(event string, payload interface{}) (*Webhook, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal the %s webhook payload due to %v", event, err)
+	}
+	delivery, err := newDeliveryID()
+	if err != nil {
+		return nil, err
+	}
+	return &Webhook{
+		Event:    event,
+		Delivery: delivery,
+		Payload:  data,
+	}, nil
+}
+
+// newDeliveryID returns a random GUID like the ones GitHub uses to identify a delivery
+func newDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate a webhook delivery ID due to %v", err)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func shortSha(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+func toWebhookRepository(repo *github.Repository, owner *webhookOwner) *webhookRepository {
+	defaultBranch := GetDefaultBranch(repo)
+	return &webhookRepository{
+		ID:            repo.GetID(),
+		Name:          repo.GetName(),
+		FullName:      repo.GetFullName(),
+		Owner:         owner,
+		Private:       repo.GetPrivate(),
+		Fork:          repo.GetFork(),
+		HTMLURL:       repo.GetHTMLURL(),
+		URL:           repo.GetHTMLURL(),
+		GitURL:        repo.GetGitURL(),
+		SSHURL:        repo.GetSSHURL(),
+		CloneURL:      repo.GetCloneURL(),
+		DefaultBranch: defaultBranch,
+		MasterBranch:  defaultBranch,
+	}
+}
+
+// String returns a description of the webhook for messages
+func (w *Webhook) String() string {
+	return fmt.Sprintf("%s webhook %s", strings.Replace(w.Event, "_", " ", -1), w.Delivery)
+}
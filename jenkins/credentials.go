@@ -0,0 +1,170 @@
+package jenkins
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+const (
+	// the default global domain of a credentials store
+	credentialsDomain = "_"
+
+	usernamePasswordCredentialsClass = "com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl"
+	secretTextCredentialsClass       = "org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl"
+)
+
+// CredentialsStore is the Jenkins system credentials store or the store of a folder
+type CredentialsStore struct {
+	// Folder is the folder of the store or empty for the system store
+	Folder JobPath
+}
+
+// Credentials are username/password credentials if the Username is set otherwise secret text credentials
+type Credentials struct {
+	ID          string
+	Description string
+	Username    string
+	Password    string
+	Secret      string
+}
+
+// credentialsXML is the XML Jenkins uses for both kinds of credentials
+type credentialsXML struct {
+	XMLName     xml.Name
+	Scope       string `xml:"scope"`
+	ID          string `xml:"id"`
+	Description string `xml:"description"`
+	Username    string `xml:"username,omitempty"`
+	Password    string `xml:"password,omitempty"`
+	Secret      string `xml:"secret,omitempty"`
+}
+
+// URLPath returns the path of the store relative to the Jenkins URL
+func (s CredentialsStore) URLPath() string {
+	if len(s.Folder) == 0 {
+		return "/credentials/store/system/domain/" + credentialsDomain
+	}
+	return s.Folder.URLPath() + "/credentials/store/folder/domain/" + credentialsDomain
+}
+
+// String returns a description of the store for messages
+func (s CredentialsStore) String() string {
+	if len(s.Folder) == 0 {
+		return "the system credentials store"
+	}
+	return "the credentials store of " + s.Folder.FullName()
+}
+
+func (s CredentialsStore) credentialsPath(id string) string {
+	return s.URLPath() + "/credential/" + url.PathEscape(id)
+}
+
+// CredentialsExist returns true if the store has credentials with the ID
+func CredentialsExist(api *utils.JenkinsAPI, store CredentialsStore, id string) (bool, error) {
+	_, err := api.Get(store.credentialsPath(id) + "/api/json")
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error looking up credentials %s in %s due to %v", id, store, err)
+	}
+	return true, nil
+}
+
+// CreateOrUpdateCredentials creates the credentials in the store or updates them if they already exist
+// returning true if they were created
+func CreateOrUpdateCredentials(api *utils.JenkinsAPI, store CredentialsStore, credentials *Credentials) (bool, error) {
+	utils.AddSecret(credentials.Password)
+	utils.AddSecret(credentials.Secret)
+	data, err := credentials.toXML()
+	if err != nil {
+		return false, err
+	}
+	exists, err := CredentialsExist(api, store, credentials.ID)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, UpdateCredentialsConfig(api, store, credentials.ID, data)
+	}
+	return true, createCredentials(api, store, credentials.ID, data)
+}
+
+func createCredentials(api *utils.JenkinsAPI, store CredentialsStore, id string, data []byte) error {
+	_, err := api.Post(store.URLPath()+"/createCredentials", "application/xml", data)
+	if err != nil {
+		return fmt.Errorf("error creating credentials %s in %s due to %v", id, store, err)
+	}
+	return nil
+}
+
+// GetCredentialsConfig returns the config.xml of the credentials in the store or nil if they do not exist.
+// Jenkins returns the secrets encrypted so the config can be posted back to restore the credentials
+func GetCredentialsConfig(api *utils.JenkinsAPI, store CredentialsStore, id string) ([]byte, error) {
+	data, err := api.Get(store.credentialsPath(id) + "/config.xml")
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting the config of credentials %s in %s due to %v", id, store, err)
+	}
+	return data, nil
+}
+
+// UpdateCredentialsConfig replaces the config.xml of the credentials in the store
+func UpdateCredentialsConfig(api *utils.JenkinsAPI, store CredentialsStore, id string, data []byte) error {
+	_, err := api.Post(store.credentialsPath(id)+"/config.xml", "application/xml", data)
+	if err != nil {
+		return fmt.Errorf("error updating credentials %s in %s due to %v", id, store, err)
+	}
+	return nil
+}
+
+// RestoreCredentialsConfig replaces the config.xml of the credentials in the store recreating them from it
+// if they have been deleted since
+func RestoreCredentialsConfig(api *utils.JenkinsAPI, store CredentialsStore, id string, data []byte) error {
+	_, err := api.Post(store.credentialsPath(id)+"/config.xml", "application/xml", data)
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return createCredentials(api, store, id, data)
+		}
+		return fmt.Errorf("error restoring credentials %s in %s due to %v", id, store, err)
+	}
+	return nil
+}
+
+// DeleteCredentials deletes the credentials from the store doing nothing if they do not exist
+func DeleteCredentials(api *utils.JenkinsAPI, store CredentialsStore, id string) error {
+	_, err := api.Post(store.credentialsPath(id)+"/doDelete", "", nil)
+	if err != nil && !utils.IsNotFound(err) {
+		return fmt.Errorf("error deleting credentials %s from %s due to %v", id, store, err)
+	}
+	return nil
+}
+
+func (c *Credentials) toXML() ([]byte, error) {
+	if c.ID == "" {
+		return nil, fmt.Errorf("error credentials have no ID")
+	}
+	value := &credentialsXML{
+		Scope:       "GLOBAL",
+		ID:          c.ID,
+		Description: c.Description,
+	}
+	if c.Username != "" {
+		value.XMLName.Local = usernamePasswordCredentialsClass
+		value.Username = c.Username
+		value.Password = c.Password
+	} else {
+		value.XMLName.Local = secretTextCredentialsClass
+		value.Secret = c.Secret
+	}
+	data, err := xml.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling credentials %s due to %v", c.ID, err)
+	}
+	return data, nil
+}
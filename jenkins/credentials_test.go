@@ -0,0 +1,241 @@
+package jenkins
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/fabric8-jenkins/godog-jenkins/github"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+// changedCredentials are credentials created or updated by the scenario which are deleted or restored at the end of it
+type changedCredentials struct {
+	Store CredentialsStore
+	ID    string
+	// Original is the config.xml of credentials which existed before the scenario updated them
+	Original []byte
+}
+
+type credentialsFeature struct {
+	Context *utils.ScenarioContext
+	Changed []*changedCredentials
+}
+
+func (f *credentialsFeature) store(folderExpression string) (CredentialsStore, error) {
+	if folderExpression == "" {
+		return CredentialsStore{}, nil
+	}
+	folder, err := ParseJobPath(f.Context, folderExpression, nil)
+	if err != nil {
+		return CredentialsStore{}, err
+	}
+	return CredentialsStore{Folder: folder}, nil
+}
+
+// createOrUpdate creates or updates the credentials registering them to be deleted, or restored if they
+// already existed, at the end of the scenario
+func (f *credentialsFeature) createOrUpdate(folderExpression string, credentials *Credentials) error {
+	store, err := f.store(folderExpression)
+	if err != nil {
+		return err
+	}
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	err = f.record(api, store, credentials.ID, false)
+	if err != nil {
+		return err
+	}
+	created, err := CreateOrUpdateCredentials(api, store, credentials)
+	if err != nil {
+		return err
+	}
+	if created {
+		f.Context.LogInfof("created credentials %s in %s\n", credentials.ID, store)
+	} else {
+		f.Context.LogInfof("updated credentials %s in %s\n", credentials.ID, store)
+	}
+	return nil
+}
+
+// record registers the credentials to be restored, or deleted if they do not exist yet, at the end of the
+// scenario before it first changes them. Credentials which must already exist are only recorded if they do
+func (f *credentialsFeature) record(api *utils.JenkinsAPI, store CredentialsStore, id string, existing bool) error {
+	if f.changed(store, id) != nil {
+		return nil
+	}
+	original, err := GetCredentialsConfig(api, store, id)
+	if err != nil {
+		return err
+	}
+	if original == nil && existing {
+		return nil
+	}
+	if len(f.Changed) == 0 {
+		f.Context.AddCleanup(f.restoreChangedCredentials)
+	}
+	f.Changed = append(f.Changed, &changedCredentials{
+		Store:    store,
+		ID:       id,
+		Original: original,
+	})
+	return nil
+}
+
+// changed returns the credentials if the scenario has already created or updated them
+func (f *credentialsFeature) changed(store CredentialsStore, id string) *changedCredentials {
+	for _, changed := range f.Changed {
+		if changed.ID == id && changed.Store.URLPath() == store.URLPath() {
+			return changed
+		}
+	}
+	return nil
+}
+
+func (f *credentialsFeature) gitHubCredentials(id string) (*Credentials, error) {
+	user, err := utils.MandatoryEnvVar("GITHUB_USER")
+	if err != nil {
+		return nil, err
+	}
+	password, err := github.GetGitHubToken()
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		password, err = utils.MandatoryEnvVar("GITHUB_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Credentials{
+		ID:          id,
+		Description: "GitHub credentials for " + user,
+		Username:    user,
+		Password:    password,
+	}, nil
+}
+
+func (f *credentialsFeature) jenkinsHasGitHubCredentialsForTheCurrentUser(id string) error {
+	return f.jenkinsHasGitHubCredentialsForTheCurrentUserInFolder(id, "")
+}
+
+func (f *credentialsFeature) jenkinsHasGitHubCredentialsForTheCurrentUserInFolder(id string, folderExpression string) error {
+	credentials, err := f.gitHubCredentials(id)
+	if err != nil {
+		return err
+	}
+	return f.createOrUpdate(folderExpression, credentials)
+}
+
+func (f *credentialsFeature) jenkinsHasUsernamePasswordCredentialsForUserWithPassword(id string, user string, password string) error {
+	return f.createOrUpdate("", &Credentials{
+		ID:       id,
+		Username: utils.ReplaceEnvVars(user),
+		Password: utils.ReplaceEnvVars(password),
+	})
+}
+
+func (f *credentialsFeature) jenkinsHasSecretTextCredentialsFromEnvVar(id string, envVar string) error {
+	secret := os.Getenv(envVar)
+	if secret == "" {
+		return fmt.Errorf("no $%s env var set for the secret text credentials %s", envVar, id)
+	}
+	return f.createOrUpdate("", &Credentials{
+		ID:     id,
+		Secret: secret,
+	})
+}
+
+func (f *credentialsFeature) credentialsShouldExist(id string, folderExpression string, expected bool) error {
+	store, err := f.store(folderExpression)
+	if err != nil {
+		return err
+	}
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	exists, err := CredentialsExist(api, store, id)
+	if err != nil {
+		return err
+	}
+	if exists != expected {
+		if expected {
+			return fmt.Errorf("error no credentials %s in %s", id, store)
+		}
+		return fmt.Errorf("error found existing credentials %s in %s", id, store)
+	}
+	return nil
+}
+
+func (f *credentialsFeature) jenkinsShouldHaveCredentials(id string) error {
+	return f.credentialsShouldExist(id, "", true)
+}
+
+func (f *credentialsFeature) jenkinsShouldNotHaveCredentials(id string) error {
+	return f.credentialsShouldExist(id, "", false)
+}
+
+func (f *credentialsFeature) iDeleteTheJenkinsCredentials(id string) error {
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	store := CredentialsStore{}
+	err = f.record(api, store, id, true)
+	if err != nil {
+		return err
+	}
+	return DeleteCredentials(api, store, id)
+}
+
+// restoreChangedCredentials deletes the credentials the scenario created and restores the ones it updated
+// as a cleanup of the scenario
+func (f *credentialsFeature) restoreChangedCredentials() {
+	defer func() {
+		f.Changed = nil
+	}()
+	if os.Getenv("BDD_KEEP_CREDENTIALS") == "true" {
+		return
+	}
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		f.Context.LogInfof("WARNING: %v\n", err)
+		return
+	}
+	for i := len(f.Changed) - 1; i >= 0; i-- {
+		changed := f.Changed[i]
+		if changed.Original != nil {
+			err := RestoreCredentialsConfig(api, changed.Store, changed.ID, changed.Original)
+			if err != nil {
+				f.Context.LogInfof("WARNING: %v\n", err)
+				continue
+			}
+			f.Context.LogInfof("restored credentials %s in %s\n", changed.ID, changed.Store)
+			continue
+		}
+		err := DeleteCredentials(api, changed.Store, changed.ID)
+		if err != nil {
+			f.Context.LogInfof("WARNING: %v\n", err)
+			continue
+		}
+		f.Context.LogInfof("deleted credentials %s from %s\n", changed.ID, changed.Store)
+	}
+}
+
+func CredentialsFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	f := &credentialsFeature{
+		Context: c,
+	}
+
+	c.Step(`^Jenkins has GitHub credentials "([^"]*)" for the current user$`, f.jenkinsHasGitHubCredentialsForTheCurrentUser)
+	c.Step(`^Jenkins has GitHub credentials "([^"]*)" for the current user in folder "([^"]*)"$`, f.jenkinsHasGitHubCredentialsForTheCurrentUserInFolder)
+	c.Step(`^Jenkins has username password credentials "([^"]*)" for user "([^"]*)" with password "([^"]*)"$`, f.jenkinsHasUsernamePasswordCredentialsForUserWithPassword)
+	c.Step(`^Jenkins has secret text credentials "([^"]*)" from \$(\w+)$`, f.jenkinsHasSecretTextCredentialsFromEnvVar)
+	c.Step(`^Jenkins should have credentials "([^"]*)"$`, f.jenkinsShouldHaveCredentials)
+	c.Step(`^Jenkins should not have credentials "([^"]*)"$`, f.jenkinsShouldNotHaveCredentials)
+	c.Step(`^I delete the Jenkins credentials "([^"]*)"$`, f.iDeleteTheJenkinsCredentials)
+}
@@ -0,0 +1,159 @@
+package jenkins
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// currentBuild is the build the running scenario of a suite is working with. The feature contexts of a suite
+// share it so that a build which one step triggers, replays or restarts can be checked, replayed or restarted
+// by the steps of the others
+type currentBuild struct {
+	Context *utils.ScenarioContext
+	Path    JobPath
+	Job     gojenkins.Job
+	Number  int
+	// Stages are the results of the stages of the build once it has finished
+	Stages []*StageResult
+
+	scenario *utils.Scenario
+	finished *gojenkins.Build
+}
+
+var (
+	currentBuildsLock sync.Mutex
+	currentBuilds     = map[*utils.ScenarioContext]*currentBuild{}
+)
+
+// getCurrentBuild returns the current build of the context which is cleared when a new scenario starts
+func getCurrentBuild(c *utils.ScenarioContext) *currentBuild {
+	currentBuildsLock.Lock()
+	defer currentBuildsLock.Unlock()
+	b := currentBuilds[c]
+	if b == nil {
+		b = &currentBuild{Context: c}
+		currentBuilds[c] = b
+	}
+	if scenario := c.Scenario(); b.scenario != scenario {
+		*b = currentBuild{Context: c, scenario: scenario}
+	}
+	return b
+}
+
+// Set makes the build of the job the current build
+func (b *currentBuild) Set(job gojenkins.Job, number int) error {
+	path, err := ParseJobURL(job.Url)
+	if err != nil {
+		return err
+	}
+	b.Path = path
+	b.Job = job
+	b.Number = number
+	b.Stages = nil
+	b.finished = nil
+	return nil
+}
+
+// SetJob makes the job current without a build so that a build number can be given for it
+func (b *currentBuild) SetJob(job gojenkins.Job) error {
+	return b.Set(job, 0)
+}
+
+// assertJob returns an error if no step has found the job of the current build yet
+func (b *currentBuild) assertJob() error {
+	if b.Job.Url == "" {
+		return fmt.Errorf("No job has been triggered, replayed or restarted yet so the job of the build is not known")
+	}
+	return nil
+}
+
+// WaitForFinish waits for the current build to finish and loads the results of its stages
+func (b *currentBuild) WaitForFinish() (*gojenkins.Build, error) {
+	if b.Number == 0 {
+		return nil, fmt.Errorf("No build has been triggered, replayed or restarted yet")
+	}
+	if b.finished != nil {
+		return b.finished, nil
+	}
+	jenkins, err := utils.GetJenkinsClient(b.Context)
+	if err != nil {
+		return nil, fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	api, err := getJenkinsAPI(b.Context)
+	if err != nil {
+		return nil, err
+	}
+	build, stages, err := WaitForBuildToFinishWithStages(b.Context, jenkins, api, b.Path, b.Job, b.Number, b.Context.Timeout(utils.TimeoutBuild))
+	if err != nil {
+		return nil, err
+	}
+	b.finished = build
+	b.Stages = stages
+	return build, nil
+}
+
+func (b *currentBuild) theBuildShouldCompleteWithResult(expectedResult string) error {
+	build, err := b.WaitForFinish()
+	if err != nil {
+		return err
+	}
+	return AssertBuildResult(b.Context, build, b.Job.Url, expectedResult)
+}
+
+func (b *currentBuild) theBuildLogShouldContain(expected string) error {
+	build, err := b.WaitForFinish()
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(b.Context)
+	if err != nil {
+		return fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	data, err := jenkins.GetBuildConsoleOutput(*build)
+	if err != nil {
+		return fmt.Errorf("error getting the log of job %s build #%d due to %v", b.Job.Url, build.Number, err)
+	}
+	expected = b.Context.ReplaceVariables(expected, nil)
+	if !strings.Contains(string(data), expected) {
+		return fmt.Errorf("the log of job %s build #%d does not contain %q", b.Job.Url, build.Number, expected)
+	}
+	return nil
+}
+
+func (b *currentBuild) theStageOfTheBuildShouldHaveStatus(stage string, expectedStatus string) error {
+	_, err := b.WaitForFinish()
+	if err != nil {
+		return err
+	}
+	for _, s := range b.Stages {
+		if s.Name == stage {
+			if s.Status != expectedStatus {
+				return fmt.Errorf("stage %s of job %s build #%d has status %s but expected %s", stage, b.Job.Url, b.Number, s.Status, expectedStatus)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("job %s build #%d has no stage %s", b.Job.Url, b.Number, stage)
+}
+
+func CurrentBuildFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	build := func() *currentBuild {
+		return getCurrentBuild(c)
+	}
+
+	c.Step(`^the (?:replayed |restarted )?build should complete with result "([^"]*)"$`, func(expectedResult string) error {
+		return build().theBuildShouldCompleteWithResult(expectedResult)
+	})
+	c.Step(`^the (?:replayed |restarted )?build log should contain "([^"]*)"$`, func(expected string) error {
+		return build().theBuildLogShouldContain(expected)
+	})
+	c.Step(`^the stage "([^"]*)" of the (?:replayed |restarted )?build should have status "([^"]*)"$`, func(stage string, expectedStatus string) error {
+		return build().theStageOfTheBuildShouldHaveStatus(stage, expectedStatus)
+	})
+}
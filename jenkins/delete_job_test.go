@@ -2,60 +2,78 @@ package jenkins
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/DATA-DOG/godog"
 	"github.com/fabric8-jenkins/godog-jenkins/utils"
 )
 
-func thereIsAJobCalled(jobExpression string) error {
-	jobName := utils.ReplaceEnvVars(jobExpression)
-	jenkins, err := utils.GetJenkinsClient()
+// jobFeature has the steps which look up, trigger and delete jobs
+type jobFeature struct {
+	Context *utils.ScenarioContext
+}
+
+func (f *jobFeature) thereIsAJobCalled(jobExpression string) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
-	paths := strings.Split(jobName, "/")
-	_, err = jenkins.GetJobByPath(paths...)
+	_, err = GetJobByPath(jenkins, jobPath)
 	if err != nil {
-		return fmt.Errorf("error finding existing job %s due to %s", jobName, err)
+		return fmt.Errorf("error finding existing job %s due to %s", jobPath.FullName(), err)
 	}
 	return nil
 }
 
-func iDeleteTheJob(jobExpression string) error {
-	jobName := utils.ReplaceEnvVars(jobExpression)
-	jenkins, err := utils.GetJenkinsClient()
+func (f *jobFeature) iDeleteTheJob(jobExpression string) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
 	if err != nil {
-		return fmt.Errorf("error getting a Jenkins client  %v", err)
+		return err
 	}
-	paths := strings.Split(jobName, "/")
-	job, err := jenkins.GetJobByPath(paths...)
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
-		return fmt.Errorf("error finding existing job %s due to %s", jobName, err)
+		return fmt.Errorf("error getting a Jenkins client  %v", err)
 	}
-	err = jenkins.DeleteJob(job)
+	exists, err := JobExists(jenkins, jobPath)
 	if err != nil {
-		return fmt.Errorf("error deleteing job %s due to %s", job.Name, err)
+		return err
 	}
-	return nil
+	if !exists {
+		return fmt.Errorf("error finding existing job %s", jobPath.FullName())
+	}
+	return DeleteJobAndWait(f.Context, jenkins, jobPath, f.Context.Timeout(utils.TimeoutJobDeleted))
 }
 
-func thereShouldNotBeAJob(jobExpression string) error {
-	jobName := utils.ReplaceEnvVars(jobExpression)
-	jenkins, err := utils.GetJenkinsClient()
+func (f *jobFeature) thereShouldNotBeAJob(jobExpression string) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client  %v", err)
 	}
 
-	job, err := jenkins.GetJob(jobName)
+	exists, err := JobExists(jenkins, jobPath)
 	if err != nil {
-		return nil
+		return err
+	}
+	if exists {
+		return fmt.Errorf("error found existing job %s", jobPath.FullName())
 	}
-	return fmt.Errorf("error found existing job %s", job.Name)
+	return nil
 }
 
 func DeleteJobFeatureContext(s *godog.Suite) {
-	s.Step(`^there is a job called "([^"]*)"$`, thereIsAJobCalled)
-	s.Step(`^I delete the "([^"]*)" job$`, iDeleteTheJob)
-	s.Step(`^there should not be a "([^"]*)" job$`, thereShouldNotBeAJob)
+	c := utils.SuiteContext(s)
+	f := &jobFeature{
+		Context: c,
+	}
+
+	c.Step(`^there is a job called "([^"]*)"$`, f.thereIsAJobCalled)
+	c.Step(`^I delete the "([^"]*)" job$`, f.iDeleteTheJob)
+	c.Step(`^there should not be a "([^"]*)" job$`, f.thereShouldNotBeAJob)
 }
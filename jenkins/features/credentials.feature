@@ -0,0 +1,12 @@
+Feature: Jenkins credentials
+  In order to run pipelines which need secrets without setting them up by hand
+  As a tester
+  I need scenarios to create the Jenkins credentials they use and remove them afterwards
+
+  Scenario: Create and delete credentials
+    Given Jenkins has GitHub credentials "godog-github" for the current user
+    And Jenkins has username password credentials "godog-user" for user "godog" with password "godog-password"
+    Then Jenkins should have credentials "godog-github"
+    And Jenkins should have credentials "godog-user"
+    When I delete the Jenkins credentials "godog-user"
+    Then Jenkins should not have credentials "godog-user"
@@ -5,11 +5,12 @@ Feature: import GitHub Repo
 
   Scenario: Import repo creates new job
     Given there is a fabric8-import job
+    And we only merge pull requests changing "Jenkinsfile"
     When we import the "fabric8-quickstarts-tests/spring-boot-http-booster" GitHub repo selecting "ReleaseAndStage" pipeline
     And we merge the PR which is created
     And the "GitHub/$GITHUB_USER/spring-boot-http-booster" scan completes successfully
-    And we trigger the "GitHub/$GITHUB_USER/spring-boot-http-booster/master" job
-    Then there should be a "GitHub/$GITHUB_USER/spring-boot-http-booster/master" job that completes successfully
+    And we trigger the "GitHub/$GITHUB_USER/spring-boot-http-booster/${DEFAULT_BRANCH}" job
+    Then there should be a "GitHub/$GITHUB_USER/spring-boot-http-booster/${DEFAULT_BRANCH}" job that completes successfully
 
 #  Scenario: Delete organisation
 #    Given there is a job called "GitHub/$GITHUB_USER"
@@ -0,0 +1,29 @@
+Feature: validate Jenkinsfiles
+  In order to find mistakes in a Jenkinsfile without waiting for a build to fail
+  As a pipeline author
+  I need Jenkins to check declarative Jenkinsfiles with its linter
+
+  Scenario: Valid declarative Jenkinsfile
+    Then the Jenkinsfile should be valid:
+      """
+      pipeline {
+        agent any
+        stages {
+          stage('Build') {
+            steps {
+              echo 'building'
+            }
+          }
+        }
+      }
+      """
+
+  Scenario: Invalid declarative Jenkinsfile
+    Then the Jenkinsfile should have an error at line 3:
+      """
+      pipeline {
+        agent any
+        stages {
+        }
+      }
+      """
@@ -0,0 +1,11 @@
+Feature: change job configuration
+  In order to test how Jenkins behaves with different job settings
+  As a tester
+  I need to be able to change the configuration of a job for a single scenario
+
+  Scenario: Change the import job and restore it afterwards
+    Given there is a job called "fabric8-import"
+    When I set the default value of parameter "repository" of job "fabric8-import" to "fabric8-quickstarts-tests/spring-boot-http-booster"
+    And I disable the job "fabric8-import"
+    Then the config of job "fabric8-import" should have "//parameterDefinitions/*[name='repository']/defaultValue" = "fabric8-quickstarts-tests/spring-boot-http-booster"
+    And the config of job "fabric8-import" should have "/*/disabled" = "true"
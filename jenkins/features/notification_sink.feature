@@ -0,0 +1,29 @@
+Feature: notification sink
+  In order to test the notifications which pipelines send
+  As a tester
+  I need to capture the calls a pipeline makes to a URL it is given
+
+  Scenario: Pipeline posts a notification to the sink
+    Given there is a job called "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And we have a clean fork of "fabric8-quickstarts-tests/spring-boot-http-booster"
+    And the git author is "godog" with email "godog@example.com"
+    When I create branch "godog-sink-${SCENARIO_ID}" in the fork
+    And I change "Jenkinsfile" in the fork to:
+      """
+      properties([parameters([string(name: 'NOTIFY_URL', defaultValue: '')])])
+      node {
+        if (params.NOTIFY_URL) {
+          sh "curl -sf -X POST -H 'Content-Type: application/json' -d '{\"status\": \"SUCCESS\"}' ${params.NOTIFY_URL}"
+        }
+      }
+      """
+    And I commit the changes in the fork with message "godog notifying the sink"
+    And I push branch "godog-sink-${SCENARIO_ID}"
+    And GitHub sends a push webhook for branch "godog-sink-${SCENARIO_ID}" of the fork
+    And the job "GitHub/$GITHUB_USER/spring-boot-http-booster/godog-sink-${SCENARIO_ID}" should start a build within 60 seconds
+    And the build should complete with result "SUCCESS"
+    Then the sink should not have received a POST to "/notify"
+    When I set the default value of parameter "NOTIFY_URL" of job "GitHub/$GITHUB_USER/spring-boot-http-booster/godog-sink-${SCENARIO_ID}" to "${SINK_URL}/notify"
+    And I trigger the "GitHub/$GITHUB_USER/spring-boot-http-booster/godog-sink-${SCENARIO_ID}" job
+    Then the build should complete with result "SUCCESS"
+    And the sink should have received a POST to "/notify" with JSON field "status" = "SUCCESS"
@@ -21,4 +21,10 @@ Feature: import GitHub Organisation
 #  Scenario: Delete organisation
 #    Given there is a job called "fabric8-quickstarts-tests"
 #    When I delete the "fabric8-quickstarts-tests" job
-#    Then there should not be a "fabric8-quickstarts-tests" job
\ No newline at end of file
+#    Then there should not be a "fabric8-quickstarts-tests" job
+  Scenario: Delete an organisation folder with the jobs inside it
+    Given there are no jobs called "fabric8-quickstarts-tests"
+    When I import the "fabric8-quickstarts-tests" GitHub organisation
+    And then wait to check the organisation scan for "fabric8-quickstarts-tests" is successful
+    And I delete the "fabric8-quickstarts-tests" job
+    Then there should not be a "fabric8-quickstarts-tests" job
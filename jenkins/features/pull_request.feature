@@ -0,0 +1,38 @@
+Feature: build pull requests
+  In order to verify changes before they are merged
+  As a developer
+  I need Jenkins to build pull requests on my fork and report the result back to GitHub
+
+  Scenario: Pull request in the fork is built
+    Given there is a job called "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And we have a clean fork of "fabric8-quickstarts-tests/spring-boot-http-booster"
+    When we create branch "godog-pr-${SCENARIO_ID}" in the fork changing "README.md"
+    And we open a pull request from branch "godog-pr-${SCENARIO_ID}" in the fork
+    Then the pull request should be discovered by "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And the pull request build should complete with result "SUCCESS"
+    And the pull request commit should have status "continuous-integration/jenkins/pr-merge" = success within 5 minutes
+
+  Scenario: Pull request changing the Jenkinsfile is built
+    Given there is a job called "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And we have a clean fork of "fabric8-quickstarts-tests/spring-boot-http-booster"
+    And the git author is "godog" with email "godog@example.com"
+    When I create branch "godog-jenkinsfile-${SCENARIO_ID}" in the fork
+    And I change "Jenkinsfile" in the fork to:
+      """
+      node {
+        checkout scm
+        echo 'changed by godog'
+      }
+      """
+    And I commit the changes in the fork with message "godog changing the Jenkinsfile"
+    And I push branch "godog-jenkinsfile-${SCENARIO_ID}"
+    And we open a pull request from branch "godog-jenkinsfile-${SCENARIO_ID}" in the fork
+    Then the pull request should be discovered by "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And the pull request build should complete with result "SUCCESS"
+
+  Scenario: Push webhook builds the branch
+    Given there is a job called "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And we have a clean fork of "fabric8-quickstarts-tests/spring-boot-http-booster"
+    When we create branch "godog-hook-${SCENARIO_ID}" in the fork changing "README.md"
+    And GitHub sends a push webhook for branch "godog-hook-${SCENARIO_ID}" of the fork
+    Then the job "GitHub/$GITHUB_USER/spring-boot-http-booster/godog-hook-${SCENARIO_ID}" should start a build within 60 seconds
@@ -0,0 +1,29 @@
+Feature: replay pipelines
+  In order to test changes to a pipeline without pushing commits
+  As a pipeline author
+  I need to replay a build with a changed script
+
+  Scenario: Replay the build of a branch with a changed script
+    Given there is a job called "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And we have a clean fork of "fabric8-quickstarts-tests/spring-boot-http-booster"
+    And the git author is "godog" with email "godog@example.com"
+    When I create branch "godog-replay-${SCENARIO_ID}" in the fork
+    And I change "Jenkinsfile" in the fork to:
+      """
+      node {
+        echo 'built by godog'
+      }
+      """
+    And I commit the changes in the fork with message "godog changing the Jenkinsfile"
+    And I push branch "godog-replay-${SCENARIO_ID}"
+    And GitHub sends a push webhook for branch "godog-replay-${SCENARIO_ID}" of the fork
+    And the job "GitHub/$GITHUB_USER/spring-boot-http-booster/godog-replay-${SCENARIO_ID}" should start a build within 60 seconds
+    And the build should complete with result "SUCCESS"
+    And I replay the build with the script:
+      """
+      node {
+        echo 'replayed by godog'
+      }
+      """
+    Then the replayed build should complete with result "SUCCESS"
+    And the replayed build log should contain "replayed by godog"
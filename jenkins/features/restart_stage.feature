@@ -0,0 +1,37 @@
+Feature: restart pipelines from a stage
+  In order to retry the end of a pipeline without running all of it again
+  As a pipeline author
+  I need to restart a declarative pipeline build from one of its stages
+
+  Scenario: Restart the build of a branch from a stage
+    Given there is a job called "GitHub/$GITHUB_USER/spring-boot-http-booster"
+    And we have a clean fork of "fabric8-quickstarts-tests/spring-boot-http-booster"
+    And the git author is "godog" with email "godog@example.com"
+    When I create branch "godog-restart-${SCENARIO_ID}" in the fork
+    And I change "Jenkinsfile" in the fork to:
+      """
+      pipeline {
+        agent any
+        stages {
+          stage('Build') {
+            steps {
+              echo 'building'
+            }
+          }
+          stage('Deploy') {
+            steps {
+              echo 'deploying'
+            }
+          }
+        }
+      }
+      """
+    And I commit the changes in the fork with message "godog changing the Jenkinsfile"
+    And I push branch "godog-restart-${SCENARIO_ID}"
+    And GitHub sends a push webhook for branch "godog-restart-${SCENARIO_ID}" of the fork
+    And the job "GitHub/$GITHUB_USER/spring-boot-http-booster/godog-restart-${SCENARIO_ID}" should start a build within 60 seconds
+    And the build should complete with result "SUCCESS"
+    And I restart the build from stage "Deploy"
+    Then the restarted build should complete with result "SUCCESS"
+    And the stage "Build" of the restarted build should have status "NOT_EXECUTED"
+    And the stage "Deploy" of the restarted build should have status "SUCCESS"
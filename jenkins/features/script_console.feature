@@ -0,0 +1,12 @@
+Feature: script console
+  In order to set up and check state which the REST API does not expose
+  As a tester
+  I need to run Groovy scripts in the Jenkins script console
+
+  Scenario: Quieting down
+    When I run the Jenkins script "quieting_down.groovy"
+    Then the script output should be "false"
+
+  Scenario: Safe listed script
+    When I run the Jenkins script "queue_size.groovy"
+    Then the script output should match "^\d+$"
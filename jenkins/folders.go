@@ -0,0 +1,100 @@
+package jenkins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// JobExists returns true if the job exists, false if Jenkins returns a 404 for it or an error for any other failure
+func JobExists(jenkins *gojenkins.Jenkins, path JobPath) (bool, error) {
+	_, err := GetJobByPath(jenkins, path)
+	if err != nil {
+		if Is404(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error looking up job %s due to %v", path.FullName(), err)
+	}
+	return true, nil
+}
+
+// ListJobs returns the paths of all of the jobs inside the folder and its sub folders
+func ListJobs(jenkins *gojenkins.Jenkins, folder JobPath) ([]JobPath, error) {
+	job, err := GetJobByPath(jenkins, folder)
+	if err != nil {
+		return nil, fmt.Errorf("error listing jobs in %s due to %v", folder.FullName(), err)
+	}
+	answer := []JobPath{}
+	for _, child := range job.Jobs {
+		childPath := folder.Child(child.Name)
+		answer = append(answer, childPath)
+		if isFolder(child) {
+			children, err := ListJobs(jenkins, childPath)
+			if err != nil {
+				return nil, err
+			}
+			answer = append(answer, children...)
+		}
+	}
+	return answer, nil
+}
+
+// DeleteJobAndWait deletes the job and waits until it and all of the jobs inside it are gone as Jenkins
+// can delete folders asynchronously. It does nothing if the job does not exist
+func DeleteJobAndWait(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, path JobPath, timeout time.Duration) error {
+	job, err := GetJobByPath(jenkins, path)
+	if err != nil {
+		if Is404(err) {
+			return nil
+		}
+		return fmt.Errorf("error finding existing job %s due to %v", path.FullName(), err)
+	}
+	// the paths are checked from the end so the job is polled until it has gone and then the jobs inside it
+	// from the deepest, stopping at the first one which still exists
+	paths := []JobPath{}
+	if isFolder(job) {
+		children, err := ListJobs(jenkins, path)
+		if err != nil {
+			return err
+		}
+		c.LogInfof("Deleting %s and the %d jobs inside it\n", path.FullName(), len(children))
+		sort.SliceStable(children, func(i, j int) bool {
+			return len(children[i]) < len(children[j])
+		})
+		paths = children
+	}
+	paths = append(paths, path)
+	err = jenkins.DeleteJob(job)
+	if err != nil {
+		return fmt.Errorf("error deleting job %s due to %v", path.FullName(), err)
+	}
+
+	fn := func() (bool, error) {
+		for len(paths) > 0 {
+			exists, err := JobExists(jenkins, paths[len(paths)-1])
+			if err != nil || exists {
+				return false, err
+			}
+			paths = paths[:len(paths)-1]
+		}
+		return true, nil
+	}
+	err = c.PollPhase(utils.TimeoutJobDeleted, 1*time.Second, timeout, fmt.Sprintf("job %s to be deleted", path.FullName()), fn)
+	if err != nil {
+		return err
+	}
+	c.RecordEvent(&utils.Event{
+		Type: utils.EventJobDeleted,
+		Job:  path.FullName(),
+	})
+	return nil
+}
+
+// isFolder returns true if the job can contain other jobs such as a folder, organisation or multibranch project
+func isFolder(job gojenkins.Job) bool {
+	return len(job.Jobs) > 0 || strings.Contains(job.Class, "Folder") || strings.Contains(job.Class, "MultiBranchProject")
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/url"
-	"strings"
 	"time"
 
 	"github.com/DATA-DOG/godog"
@@ -15,13 +14,8 @@ import (
 	gh "github.com/google/go-github/github"
 )
 
-const (
-	maxWaitForBuildToStart     = 20 * time.Second
-	maxWaitForBuildToBeCreated = 50 * time.Second
-	maxWaitForBuildToComplete  = 40 * time.Minute
-)
-
 type importFeature struct {
+	Context              *utils.ScenarioContext
 	job                  gojenkins.Job
 	GitHubClient         *gh.Client
 	Jenkins              *gojenkins.Jenkins
@@ -29,16 +23,20 @@ type importFeature struct {
 	ImportJobName        string
 	LastBuildNumber      int
 	TriggeredBuildNumber int
+	MergePolicy          *github.MergePolicy
+	MergedPullRequests   []*github.MergedPullRequest
+	IgnoredPullRequests  map[int]bool
+	Variables            map[string]string
 }
 
 func (f *importFeature) thereIsAFabricImportJob(arg int) error {
-	jenkins, err := utils.GetJenkinsClient()
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
 
 	/*
-	err = WaitForBuildLog(jenkins, "/job/GitHub/job/jstrachan/job/spring-boot-http-booster/job/master/3", maxWaitForImportBuildToComplete)
+	err = WaitForBuildLog(f.Context, jenkins, "/job/GitHub/job/jstrachan/job/spring-boot-http-booster/job/master/3", maxWaitForImportBuildToComplete)
 	if err != nil {
 		return fmt.Errorf("Failed to tail log %v", err)
 	}
@@ -46,6 +44,8 @@ func (f *importFeature) thereIsAFabricImportJob(arg int) error {
 	*/
 
 	jobName := f.ImportJobName
+	// the import job works out its builds from the last build number so only one scenario may trigger it at a time
+	f.Context.LockResource("job " + jobName)
 	f.job, err = jenkins.GetJob(jobName)
 	if err != nil {
 		jobXML, err := utils.GetFileAsString("resources/import_job.xml")
@@ -57,6 +57,10 @@ func (f *importFeature) thereIsAFabricImportJob(arg int) error {
 		if err != nil {
 			return fmt.Errorf("error creating Job %v", err)
 		}
+		f.Context.RecordEvent(&utils.Event{
+			Type: utils.EventJobCreated,
+			Job:  jobName,
+		})
 		f.job, err = jenkins.GetJob(jobName)
 		if err != nil {
 			return fmt.Errorf("error creating Job %v", err)
@@ -67,18 +71,20 @@ func (f *importFeature) thereIsAFabricImportJob(arg int) error {
 
 func (f *importFeature) weImportTheGitHubRepoSelectingPipeline(originalRepoName, pipeline string) error {
 	// lets fork the repository first
-	utils.LogInfof("forking upstream %s\n", originalRepoName)
+	f.Context.LogInfof("forking upstream %s\n", originalRepoName)
 	forker := &github.ForkFeature{
-		GitCommander: github.CreateGitCommander(),
+		Context:      f.Context,
+		GitCommander: github.CreateGitCommander(f.Context),
 	}
 	repository, err := forker.ForkToUsersRepo(originalRepoName)
 	if err != nil {
 		return err
 	}
 	f.ForkedRepository = repository
-	utils.LogInfof("fork is %s\n", repository)
+	f.Variables = forker.Variables()
+	f.Context.LogInfof("fork is %s\n", repository)
 
-	jenkins, err := utils.GetJenkinsClient()
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
@@ -102,6 +108,10 @@ func (f *importFeature) weImportTheGitHubRepoSelectingPipeline(originalRepoName,
 	if err != nil {
 		return fmt.Errorf("error triggering Job %s %v", f.job.Name, err)
 	}
+	f.Context.RecordEvent(&utils.Event{
+		Type: utils.EventBuildTriggered,
+		Job:  jobFullName(f.job),
+	})
 	return nil
 }
 
@@ -116,26 +126,33 @@ func (f *importFeature) weMergeThePRWhichIsCreated() error {
 	jenkins := f.Jenkins
 
 	// lets keep polling for pending PRs
-	ghc, err := github.CreateGitHubClient()
+	ghc, err := github.CreateGitHubClient(f.Context)
 	if err != nil {
 		return err
 	}
 	f.GitHubClient = ghc
 
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
+	}
+
 	ctx := context.Background()
 	prOpts := &gh.PullRequestListOptions{
 		State: "open",
 	}
 	loggedNotStarted := false
+	// waitingPullRequests are the pull requests which do not change the expected files yet
+	waitingPullRequests := map[int]bool{}
 
 	newBuildNumber := -1
 
 	//  wait for the import to complete merging open PRs if we find any
-	for {
+	fn := func() (bool, error) {
 		importJob := f.ImportJobName
 		job, err := jenkins.GetJob(importJob)
 		if err != nil {
-			utils.LogInfof("WARNING: could not find import job %s due to %v\n", importJob, err)
+			f.Context.LogInfof("WARNING: could not find import job %s due to %v\n", importJob, err)
 		}
 		var build gojenkins.Build
 		if newBuildNumber < 0 {
@@ -150,71 +167,150 @@ func (f *importFeature) weMergeThePRWhichIsCreated() error {
 			build, err = jenkins.GetBuild(job, newBuildNumber)
 		}
 		if err != nil {
-			utils.LogInfof("WARNING: could not find last build of job %s due to %v\n", importJob, err)
+			f.Context.LogInfof("WARNING: could not find last build of job %s due to %v\n", importJob, err)
 		} else {
 			if build.Number == f.LastBuildNumber {
 				if !loggedNotStarted {
 					loggedNotStarted = true
-					utils.LogInfof("import job not started yet. Last build is still #%d\n", build.Number)
+					f.Context.LogInfof("import job not started yet. Last build is still #%d\n", build.Number)
 				}
-				continue
+				return false, nil
 			}
 			if newBuildNumber < 0 {
 				newBuildNumber = build.Number
-				utils.LogInfof("import job started build #%d\n", newBuildNumber)
+				f.Context.LogInfof("import job started build #%d\n", newBuildNumber)
+				f.Context.RecordEvent(&utils.Event{
+					Type:  utils.EventBuildStarted,
+					Job:   jobFullName(job),
+					Build: newBuildNumber,
+				})
 			}
 			if !build.Building {
-				return AssertBuildSucceeded(&build, importJob)
+				f.Context.RecordEvent(&utils.Event{
+					Type:   utils.EventBuildFinished,
+					Job:    jobFullName(job),
+					Build:  build.Number,
+					Result: build.Result,
+				})
+				return true, AssertBuildSucceeded(f.Context, &build, importJob)
 			}
 		}
 
 		prs, _, err := ghc.PullRequests.List(ctx, owner, name, prOpts)
 		if err != nil {
-			return fmt.Errorf("Failed to poll PullRequests on repository %s/%s due to %v", owner, name, err)
+			return false, fmt.Errorf("Failed to poll PullRequests on repository %s/%s due to %v", owner, name, err)
 		}
 		for _, pr := range prs {
-			url := ""
-			title := ""
-			if pr.HTMLURL != nil {
-				url = *pr.HTMLURL
+			if pr.Number == nil {
+				continue
 			}
-			if pr.Title != nil {
-				title = *pr.Title
+			n := *pr.Number
+			if f.IgnoredPullRequests[n] {
+				continue
 			}
-
-			if pr.Number != nil {
-				n := *pr.Number
-				mergeOpts := &gh.PullRequestOptions{
-					MergeMethod: "rebase",
-				}
-				//utils.LogInfof("Merging PR %s %s\n", url, title)
-				r, _, err := ghc.PullRequests.Merge(ctx, owner, name, n, "godog merging", mergeOpts)
-				if err != nil {
-					return fmt.Errorf("Failed to merge PR %s due to %v", url, err)
-				}
-				if r.Merged != nil && *r.Merged {
-					utils.LogInfof("merged PR %s %s\n", url, title)
-				} else {
-					return fmt.Errorf("Failed to merge PR %s got result %v", url, r)
+			matches, reason := policy.Matches(pr)
+			if !matches {
+				f.IgnoredPullRequests[n] = true
+				f.Context.LogInfof("ignoring PR #%d as %s\n", n, reason)
+				continue
+			}
+			changes, reason, err := policy.ChangesExpectedFiles(ghc, repoName, pr)
+			if err != nil {
+				return false, err
+			}
+			if !changes {
+				if !waitingPullRequests[n] {
+					waitingPullRequests[n] = true
+					f.Context.LogInfof("waiting to merge PR #%d as %s\n", n, reason)
 				}
+				continue
 			}
+			merged, err := policy.Merge(ghc, repoName, pr)
+			if err != nil {
+				return false, err
+			}
+			f.MergedPullRequests = append(f.MergedPullRequests, merged)
 		}
-		time.Sleep(5 * time.Second)
+		return false, nil
+	}
+	return f.Context.PollPhase(utils.TimeoutBuild, 5*time.Second, f.Context.Timeout(utils.TimeoutBuild), fmt.Sprintf("the %s job to import %s", f.ImportJobName, f.ForkedRepository), fn)
+}
+
+// mergePolicy lazily creates the merge policy from the environment so that steps can customise it
+func (f *importFeature) mergePolicy() (*github.MergePolicy, error) {
+	if f.MergePolicy == nil {
+		policy, err := github.CreateMergePolicy(f.Context)
+		if err != nil {
+			return nil, err
+		}
+		f.MergePolicy = policy
+	}
+	return f.MergePolicy, nil
+}
+
+func (f *importFeature) weOnlyMergePullRequestsFrom(authors string) error {
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
+	}
+	policy.Authors = github.SplitList(utils.ReplaceEnvVars(authors))
+	return nil
+}
+
+func (f *importFeature) weOnlyMergePullRequestsWithTitleMatching(expression string) error {
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
+	}
+	return policy.SetTitleRegex(expression)
+}
+
+func (f *importFeature) weOnlyMergePullRequestsFromBranchesMatching(expression string) error {
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
+	}
+	return policy.SetBranchRegex(expression)
+}
+
+func (f *importFeature) weOnlyMergePullRequestsChanging(fileName string) error {
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
+	}
+	policy.ExpectedFiles = append(policy.ExpectedFiles, fileName)
+	return nil
+}
+
+func (f *importFeature) weMergePullRequestsUsingTheMethod(method string) error {
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
 	}
+	return policy.SetMergeMethod(method)
+}
+
+func (f *importFeature) weMergePullRequestsAfterTheirStatusChecksPass() error {
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
+	}
+	policy.WaitForStatusChecks = true
+	return nil
 }
 
 func (f *importFeature) weTriggerTheJob(jobExpression string) error {
-	job, err := f.waitForJobByExpression(jobExpression, maxWaitForBuildToBeCreated)
+	job, err := f.waitForJobByExpression(jobExpression, f.Context.Timeout(utils.TimeoutBuildCreated))
 	if err != nil {
 		return err
 	}
 	jenkins := f.Jenkins
-	build, err := TriggerAndWaitForBuildToFinish(jenkins, job, maxWaitForBuildToStart, maxWaitForBuildToComplete)
+	build, err := TriggerAndWaitForBuildToFinish(f.Context, jenkins, job, f.Context.Timeout(utils.TimeoutBuildStart), f.Context.Timeout(utils.TimeoutBuild))
 	if err != nil {
 		return err
 	}
 	f.TriggeredBuildNumber = build.Number
-	return nil
+	return getCurrentBuild(f.Context).Set(job, build.Number)
 }
 
 func (f *importFeature) theScanCompletesSuccessfully(jobExpression string) error {
@@ -224,27 +320,33 @@ func (f *importFeature) theScanCompletesSuccessfully(jobExpression string) error
 }
 
 func (f *importFeature) thereShouldBeAJobThatCompletesSuccessfully(jobExpression string) error {
-	job, err := f.waitForJobByExpression(jobExpression, maxWaitForBuildToBeCreated)
+	job, err := f.waitForJobByExpression(jobExpression, f.Context.Timeout(utils.TimeoutBuildCreated))
 	if err != nil {
 		return err
 	}
 	jenkins := f.Jenkins
-	build, err := WaitForBuildToFinish(jenkins, job, f.TriggeredBuildNumber, maxWaitForBuildToComplete)
+	build, err := WaitForBuildToFinish(f.Context, jenkins, job, f.TriggeredBuildNumber, f.Context.Timeout(utils.TimeoutBuild))
 	if err != nil {
 		return err
 	}
-	return AssertBuildSucceeded(build, job.Url)
+	return AssertBuildSucceeded(f.Context, build, job.Url)
 }
 
 func (f *importFeature) waitForJobByExpression(jobExpression string, timeout time.Duration) (job gojenkins.Job, err error) {
-	jobPath := utils.ReplaceEnvVars(jobExpression)
-	jenkins := f.Jenkins
+	return waitForJobByExpression(f.Context, f.Jenkins, jobExpression, f.Variables, timeout)
+}
 
-	paths := strings.Split(jobPath, "/")
-	fullPath := gojenkins.FullJobPath(paths...)
+// waitForJobByExpression waits for the job with the given expression to be created replacing any of the
+// variables or environment variables in the expression
+func waitForJobByExpression(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, jobExpression string, variables map[string]string, timeout time.Duration) (job gojenkins.Job, err error) {
+	jobPath, err := ParseJobPath(c, jobExpression, variables)
+	if err != nil {
+		return
+	}
+	fullPath := jobPath.URLPath()
 
 	fn := func() (bool, error) {
-		job, err = jenkins.GetJobByPath(paths...)
+		job, err = GetJobByPath(jenkins, jobPath)
 		if err != nil {
 			if !Is404(err) {
 				err = fmt.Errorf("Failed to find job %s due to %v", fullPath, err)
@@ -255,36 +357,62 @@ func (f *importFeature) waitForJobByExpression(jobExpression string, timeout tim
 		}
 		return false, nil
 	}
-	err = gojenkins.Poll(1*time.Second, timeout, fmt.Sprintf("build to be created for %s", fullPath), fn)
+	err = c.PollPhase(utils.TimeoutBuildCreated, 1*time.Second, timeout, fmt.Sprintf("build to be created for %s", fullPath), fn)
 	return
 }
 
 func (f *importFeature) getJobByExpression(jobExpression string) (job gojenkins.Job, err error) {
-	jobPath := utils.ReplaceEnvVars(jobExpression)
-	jenkins := f.Jenkins
-
-	paths := strings.Split(jobPath, "/")
-	fullPath := gojenkins.FullJobPath(paths...)
-
-	job, err = jenkins.GetJobByPath(paths...)
+	jobPath, err := ParseJobPath(f.Context, jobExpression, f.Variables)
+	if err != nil {
+		return
+	}
+	job, err = GetJobByPath(f.Jenkins, jobPath)
 	if err != nil {
-		err = fmt.Errorf("Failed to find job %s due to %v", fullPath, err)
+		err = fmt.Errorf("Failed to find job %s due to %v", jobPath.URLPath(), err)
 	}
 	return
 }
 
 func FeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	s.BeforeScenario(func(interface{}) {
+		c.AddCleanup(func() {
+			github.LogRateLimit(c)
+		})
+	})
 }
 
 func FeatureImportContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
 	f := &importFeature{
+		Context:       c,
 		ImportJobName: "fabric8-import",
 	}
 
-	s.Step(`^there is a fabric(\d+)-import job$`, f.thereIsAFabricImportJob)
-	s.Step(`^we import the "([^"]*)" GitHub repo selecting "([^"]*)" pipeline$`, f.weImportTheGitHubRepoSelectingPipeline)
-	s.Step(`^we merge the PR which is created$`, f.weMergeThePRWhichIsCreated)
-	s.Step(`^the "([^"]*)" scan completes successfully$`, f.theScanCompletesSuccessfully)
-	s.Step(`^we trigger the "([^"]*)" job$`, f.weTriggerTheJob)
-	s.Step(`^there should be a "([^"]*)" job that completes successfully$`, f.thereShouldBeAJobThatCompletesSuccessfully)
+	s.BeforeScenario(func(interface{}) {
+		f.MergePolicy = nil
+		f.MergedPullRequests = nil
+		f.IgnoredPullRequests = map[int]bool{}
+		f.Variables = nil
+		// lets report the merged PRs in the log of the scenario
+		c.AddCleanup(func() {
+			for _, merged := range f.MergedPullRequests {
+				c.LogInfof("merged PR %s\n", merged.String())
+			}
+		})
+	})
+
+	c.Step(`^there is a fabric(\d+)-import job$`, f.thereIsAFabricImportJob)
+	c.Step(`^we only merge pull requests from "([^"]*)"$`, f.weOnlyMergePullRequestsFrom)
+	c.Step(`^we only merge pull requests with a title matching "([^"]*)"$`, f.weOnlyMergePullRequestsWithTitleMatching)
+	c.Step(`^we only merge pull requests from branches matching "([^"]*)"$`, f.weOnlyMergePullRequestsFromBranchesMatching)
+	c.Step(`^we only merge pull requests changing "([^"]*)"$`, f.weOnlyMergePullRequestsChanging)
+	c.Step(`^we merge pull requests using the "(merge|squash|rebase)" method$`, f.weMergePullRequestsUsingTheMethod)
+	c.Step(`^we merge pull requests after their status checks pass$`, f.weMergePullRequestsAfterTheirStatusChecksPass)
+	c.Step(`^we validate the Jenkinsfile of pull requests before merging them$`, f.weValidateTheJenkinsfileOfPullRequestsBeforeMergingThem)
+	c.Step(`^we import the "([^"]*)" GitHub repo selecting "([^"]*)" pipeline$`, f.weImportTheGitHubRepoSelectingPipeline)
+	c.Step(`^we merge the PR which is created$`, f.weMergeThePRWhichIsCreated)
+	c.Step(`^the "([^"]*)" scan completes successfully$`, f.theScanCompletesSuccessfully)
+	c.Step(`^we trigger the "([^"]*)" job$`, f.weTriggerTheJob)
+	c.Step(`^there should be a "([^"]*)" job that completes successfully$`, f.thereShouldBeAJobThatCompletesSuccessfully)
 }
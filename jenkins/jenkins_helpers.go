@@ -2,7 +2,6 @@ package jenkins
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -18,26 +17,59 @@ func Is404(err error) bool {
 	return strings.HasPrefix(text, "404 ")
 }
 
+// getJenkinsAPI returns a JenkinsAPI which records its requests against the running scenario of the context
+func getJenkinsAPI(c *utils.ScenarioContext) (*utils.JenkinsAPI, error) {
+	api, err := utils.GetJenkinsAPI(c)
+	if err != nil {
+		return nil, fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	return api, nil
+}
+
+// jobFullName returns the full name of the job which events are recorded with, or its URL if that is not a job URL
+func jobFullName(job gojenkins.Job) string {
+	path, err := ParseJobURL(job.Url)
+	if err != nil {
+		return job.Url
+	}
+	return path.FullName()
+}
+
 // TriggerAndWaitForBuildToStart triggers the build and waits for a new Build for the given amount of time
 // or returns an error
-func TriggerAndWaitForBuildToStart(jenkins *gojenkins.Jenkins, job gojenkins.Job, buildStartWaitTime time.Duration) (result *gojenkins.Build, err error) {
+func TriggerAndWaitForBuildToStart(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, job gojenkins.Job, buildStartWaitTime time.Duration) (result *gojenkins.Build, err error) {
+	trigger := func() error {
+		err := jenkins.Build(job, nil)
+		if err != nil && !Is404(err) {
+			return fmt.Errorf("error triggering build %s due to %v", job.Url, err)
+		}
+		return nil
+	}
+	return triggerWithAndWaitForBuildToStart(c, jenkins, job, trigger, buildStartWaitTime)
+}
+
+// triggerWithAndWaitForBuildToStart starts a build of the job using the trigger function and waits for a
+// new Build for the given amount of time or returns an error
+func triggerWithAndWaitForBuildToStart(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, job gojenkins.Job, trigger func() error, buildStartWaitTime time.Duration) (result *gojenkins.Build, err error) {
 	previousBuildNumber := 0
 	previousBuild, err := jenkins.GetLastBuild(job)
 	jobUrl := job.Url
 	if err != nil {
 		if !Is404(err) {
 			//return nil, fmt.Errorf("error finding last build for %s due to %v", job.Name, err)
-			utils.LogInfof("Warning: error finding previous build for %s due to %v\n", jobUrl, err)
+			c.LogInfof("Warning: error finding previous build for %s due to %v\n", jobUrl, err)
 		}
 	} else {
 		previousBuildNumber = previousBuild.Number
 	}
-	err = jenkins.Build(job, nil)
+	err = trigger()
 	if err != nil {
-		if !Is404(err) {
-			return nil, fmt.Errorf("error triggering build %s due to %v", jobUrl, err)
-		}
+		return nil, err
 	}
+	c.RecordEvent(&utils.Event{
+		Type: utils.EventBuildTriggered,
+		Job:  jobFullName(job),
+	})
 	attempts := 0
 
 	// lets wait for a new build to start
@@ -48,41 +80,46 @@ func TriggerAndWaitForBuildToStart(jenkins *gojenkins.Jenkins, job gojenkins.Job
 		if err != nil {
 			if !Is404(err) {
 				//return nil, fmt.Errorf("error finding last build for %s due to %v", job.Name, err)
-				utils.LogInfof("Warning: error finding last build attempt %d for %s due to %v\n", attempts, jobUrl, err)
+				c.LogInfof("Warning: error finding last build attempt %d for %s due to %v\n", attempts, jobUrl, err)
 			}
 		} else {
 			buildNumber = build.Number
 		}
 		if previousBuildNumber != buildNumber {
-			utils.LogInfof("triggered job %s build #%d\n", jobUrl, buildNumber)
+			c.LogInfof("triggered job %s build #%d\n", jobUrl, buildNumber)
+			c.RecordEvent(&utils.Event{
+				Type:  utils.EventBuildStarted,
+				Job:   jobFullName(job),
+				Build: buildNumber,
+			})
 			result = &build
 			return true, nil
 		}
 		return false, nil
 	}
-	err = gojenkins.Poll(1*time.Second, buildStartWaitTime, fmt.Sprintf("build to start for for %s", jobUrl), fn)
+	err = c.PollPhase(utils.TimeoutBuildStart, 1*time.Second, buildStartWaitTime, fmt.Sprintf("build to start for for %s", jobUrl), fn)
 	return
 }
 
 // TriggerAndWaitForBuildToStart triggers the build and waits for a new Build then waits for the Build to finish
 // or returns an error
-func TriggerAndWaitForBuildToFinish(jenkins *gojenkins.Jenkins, job gojenkins.Job, buildStartWaitTime time.Duration, buildFinishWaitTime time.Duration) (*gojenkins.Build, error) {
-	build, err := TriggerAndWaitForBuildToStart(jenkins, job, buildStartWaitTime)
+func TriggerAndWaitForBuildToFinish(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, job gojenkins.Job, buildStartWaitTime time.Duration, buildFinishWaitTime time.Duration) (*gojenkins.Build, error) {
+	build, err := TriggerAndWaitForBuildToStart(c, jenkins, job, buildStartWaitTime)
 	if err != nil {
 		return build, err
 	}
 	if (!build.Building) {
 		return build, nil
 	}
-	return WaitForBuildToFinish(jenkins, job, build.Number, buildFinishWaitTime)
+	return WaitForBuildToFinish(c, jenkins, job, build.Number, buildFinishWaitTime)
 }
 
 // TriggerAndWaitForBuildToStart triggers the build and waits for a new Build then waits for the Build to finish
 // or returns an error
-func WaitForBuildToFinish(jenkins *gojenkins.Jenkins, job gojenkins.Job, buildNumber int, buildFinishWaitTime time.Duration) (*gojenkins.Build, error) {
+func WaitForBuildToFinish(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, job gojenkins.Job, buildNumber int, buildFinishWaitTime time.Duration) (*gojenkins.Build, error) {
 	jobUrl := job.Url
-	utils.LogInfof("waiting for job %s build #%d to finish\n", jobUrl, buildNumber)
-	time.Sleep(1 * time.Second)
+	c.LogInfof("waiting for job %s build #%d to finish\n", jobUrl, buildNumber)
+	utils.Sleep(1 * time.Second)
 	var result *gojenkins.Build
 
 	fn := func() (bool, error) {
@@ -95,11 +132,17 @@ func WaitForBuildToFinish(jenkins *gojenkins.Jenkins, job gojenkins.Job, buildNu
 		}
 		if !b.Building {
 			result = &b
+			c.RecordEvent(&utils.Event{
+				Type:   utils.EventBuildFinished,
+				Job:    jobFullName(job),
+				Build:  buildNumber,
+				Result: b.Result,
+			})
 			return true, nil
 		}
 		return false, nil
 	}
-	writer := utils.NewPrefixWriter(os.Stdout, jenkinsLogPrefix)
+	writer := utils.NewPrefixWriter(c.Output(), jenkinsLogPrefix)
 	logFn := jenkins.TailLogFunc(jenkins.GetBuildURL(job, buildNumber), writer)
 	/*
 	poller := jenkins.NewLogPoller(jenkins.GetBuildURL(job, buildNumber), os.Stdout)
@@ -108,29 +151,60 @@ func WaitForBuildToFinish(jenkins *gojenkins.Jenkins, job gojenkins.Job, buildNu
 	}
 	*/
 	fns := gojenkins.NewConditionFunc(fn, logFn)
-	err := gojenkins.Poll(1*time.Second, buildFinishWaitTime, fmt.Sprintf("job %s build #%d to finish", jobUrl, buildNumber), fns)
+	err := c.PollPhase(utils.TimeoutBuild, 1*time.Second, buildFinishWaitTime, fmt.Sprintf("job %s build #%d to finish", jobUrl, buildNumber), fns)
+	writer.Close()
 	return result, err
 }
 
+// WaitForLastBuildToFinish waits for the job to have a build, such as one triggered by a branch indexing, then waits
+// for the last build to finish or returns an error
+func WaitForLastBuildToFinish(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, job gojenkins.Job, buildStartWaitTime time.Duration, buildFinishWaitTime time.Duration) (*gojenkins.Build, error) {
+	jobUrl := job.Url
+	var result *gojenkins.Build
+	fn := func() (bool, error) {
+		build, err := jenkins.GetLastBuild(job)
+		if err != nil {
+			if Is404(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("error finding last build for %s due to %v", jobUrl, err)
+		}
+		result = &build
+		return true, nil
+	}
+	err := c.PollPhase(utils.TimeoutBuildStart, 1*time.Second, buildStartWaitTime, fmt.Sprintf("a build to start for %s", jobUrl), fn)
+	if err != nil {
+		return result, err
+	}
+	if !result.Building {
+		return result, nil
+	}
+	return WaitForBuildToFinish(c, jenkins, job, result.Number, buildFinishWaitTime)
+}
+
 // WaitForBuildLog
-func WaitForBuildLog(jenkins *gojenkins.Jenkins, buildURL string, buildFinishWaitTime time.Duration) error {
-	utils.LogInfof("waiting for job %s to finish\n", buildURL)
-	time.Sleep(1 * time.Second)
+func WaitForBuildLog(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, buildURL string, buildFinishWaitTime time.Duration) error {
+	c.LogInfof("waiting for job %s to finish\n", buildURL)
+	utils.Sleep(1 * time.Second)
 
-	poller := jenkins.NewLogPoller(buildURL, os.Stdout)
+	poller := jenkins.NewLogPoller(buildURL, c.Output())
 	logFn := func() (bool, error) {
 		return poller.Apply()
 	}
-	return gojenkins.Poll(1*time.Second, buildFinishWaitTime, fmt.Sprintf("waiting for job %s to finish\n", buildURL), logFn)
+	return c.PollPhase(utils.TimeoutBuild, 1*time.Second, buildFinishWaitTime, fmt.Sprintf("waiting for job %s to finish\n", buildURL), logFn)
 }
 
 // AssertBuildSucceeded asserts that the given build succeeded
-func AssertBuildSucceeded(build *gojenkins.Build, jobName string) error {
+func AssertBuildSucceeded(c *utils.ScenarioContext, build *gojenkins.Build, jobName string) error {
+	return AssertBuildResult(c, build, jobName, "SUCCESS")
+}
+
+// AssertBuildResult asserts that the given build has the expected result
+func AssertBuildResult(c *utils.ScenarioContext, build *gojenkins.Build, jobName string, expectedResult string) error {
 	result := build.Result
-	utils.LogInfof("Job %s build %d has result %s\n", jobName, build.Number, result)
-	if result == "SUCCESS" {
+	c.LogInfof("Job %s build %d has result %s\n", jobName, build.Number, result)
+	if result == expectedResult {
 		return nil
 	}
-	return fmt.Errorf("Job %s build %d has result %s", jobName, build.Number, result)
-
+	return fmt.Errorf("Job %s build %d has result %s but expected %s", jobName, build.Number, result, expectedResult)
 }
@@ -0,0 +1,123 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+const (
+	jenkinsfileValidatePath = "pipeline-model-converter/validate"
+
+	jenkinsfileValidMessage         = "successfully validated"
+	jenkinsfileNotDeclarativeMarker = "did not contain the 'pipeline' step"
+)
+
+// jenkinsfileErrorPattern matches an error of the declarative linter such as
+// `WorkflowScript: 3: Expected a stage @ line 3, column 5.`
+var jenkinsfileErrorPattern = regexp.MustCompile(`(?m)^WorkflowScript: (\d+): (.*?)(?: @ line (\d+), column (\d+)\.)?\s*$`)
+
+// JenkinsfileError is an error reported by the declarative linter
+type JenkinsfileError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *JenkinsfileError) String() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	if e.Column == 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// JenkinsfileValidation is the result of validating a Jenkinsfile with the declarative linter
+type JenkinsfileValidation struct {
+	Valid bool
+	// Declarative is false for scripted pipelines which the linter cannot validate
+	Declarative bool
+	Errors      []*JenkinsfileError
+	// Output is the text returned by Jenkins
+	Output string
+}
+
+// ValidateJenkinsfile validates the declarative Jenkinsfile using the pipeline model converter of Jenkins
+func ValidateJenkinsfile(api *utils.JenkinsAPI, jenkinsfile string) (*JenkinsfileValidation, error) {
+	data, err := api.PostForm(jenkinsfileValidatePath, url.Values{"jenkinsfile": {jenkinsfile}})
+	if err != nil {
+		return nil, fmt.Errorf("error validating the Jenkinsfile due to %v", err)
+	}
+	return parseJenkinsfileValidation(string(data)), nil
+}
+
+func parseJenkinsfileValidation(output string) *JenkinsfileValidation {
+	answer := &JenkinsfileValidation{
+		Valid:       strings.Contains(output, jenkinsfileValidMessage),
+		Declarative: !strings.Contains(output, jenkinsfileNotDeclarativeMarker),
+		Output:      output,
+	}
+	if answer.Valid || !answer.Declarative {
+		return answer
+	}
+	for _, match := range jenkinsfileErrorPattern.FindAllStringSubmatch(output, -1) {
+		e := &JenkinsfileError{
+			Message: match[2],
+		}
+		e.Line, _ = strconv.Atoi(match[1])
+		if match[3] != "" {
+			e.Line, _ = strconv.Atoi(match[3])
+			e.Column, _ = strconv.Atoi(match[4])
+		}
+		answer.Errors = append(answer.Errors, e)
+	}
+	if len(answer.Errors) == 0 {
+		answer.Errors = append(answer.Errors, &JenkinsfileError{
+			Message: strings.TrimSpace(output),
+		})
+	}
+	return answer
+}
+
+// HasErrorAtLine returns true if the linter reported an error on the line
+func (v *JenkinsfileValidation) HasErrorAtLine(line int) bool {
+	for _, e := range v.Errors {
+		if e.Line == line {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertValid returns an error listing the errors of the linter if the Jenkinsfile is not valid.
+// Scripted pipelines cannot be validated so they fail unless $BDD_SKIP_SCRIPTED_JENKINSFILES is true
+// in which case they only log a warning
+func (v *JenkinsfileValidation) AssertValid(c *utils.ScenarioContext, name string) error {
+	if !v.Declarative {
+		if os.Getenv("BDD_SKIP_SCRIPTED_JENKINSFILES") == "true" {
+			c.LogInfof("WARNING: cannot validate %s as it is not a declarative pipeline\n", name)
+			return nil
+		}
+		return fmt.Errorf("%s cannot be validated as it is not a declarative pipeline. Set $BDD_SKIP_SCRIPTED_JENKINSFILES=true to skip scripted pipelines", name)
+	}
+	if v.Valid {
+		c.LogInfof("validated %s\n", name)
+		return nil
+	}
+	return fmt.Errorf("%s is not valid:\n%s", name, v.describeErrors())
+}
+
+func (v *JenkinsfileValidation) describeErrors() string {
+	lines := []string{}
+	for _, e := range v.Errors {
+		lines = append(lines, "  "+e.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,108 @@
+package jenkins
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/DATA-DOG/godog/gherkin"
+	"github.com/fabric8-jenkins/godog-jenkins/github"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+
+	gh "github.com/google/go-github/github"
+)
+
+const jenkinsfileName = "Jenkinsfile"
+
+// jenkinsfileFeature has the steps which validate Jenkinsfiles given in the feature
+type jenkinsfileFeature struct {
+	Context *utils.ScenarioContext
+}
+
+func validateJenkinsfile(c *utils.ScenarioContext, jenkinsfile string) (*JenkinsfileValidation, error) {
+	api, err := getJenkinsAPI(c)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateJenkinsfile(api, jenkinsfile)
+}
+
+func (f *jenkinsfileFeature) theJenkinsfileShouldBeValid(content *gherkin.DocString) error {
+	validation, err := validateJenkinsfile(f.Context, content.Content)
+	if err != nil {
+		return err
+	}
+	return validation.AssertValid(f.Context, "the Jenkinsfile")
+}
+
+func (f *jenkinsfileFeature) theJenkinsfileShouldHaveAnErrorAtLine(line int, content *gherkin.DocString) error {
+	validation, err := validateJenkinsfile(f.Context, content.Content)
+	if err != nil {
+		return err
+	}
+	if !validation.Declarative {
+		return fmt.Errorf("the Jenkinsfile is not a declarative pipeline")
+	}
+	if validation.Valid {
+		return fmt.Errorf("the Jenkinsfile is valid but expected an error at line %d", line)
+	}
+	if !validation.HasErrorAtLine(line) {
+		return fmt.Errorf("expected an error at line %d of the Jenkinsfile but found:\n%s", line, validation.describeErrors())
+	}
+	return nil
+}
+
+func (p *pullRequestFeature) theFileInTheForkShouldBeAValidJenkinsfile(path string) error {
+	if p.Forker == nil {
+		return fmt.Errorf("No fork has been created yet")
+	}
+	fileName := filepath.Join(p.Forker.ForkDir, path)
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("error reading %s due to %v", fileName, err)
+	}
+	validation, err := validateJenkinsfile(p.Context, string(data))
+	if err != nil {
+		return err
+	}
+	return validation.AssertValid(p.Context, path+" in the fork")
+}
+
+func (p *pullRequestFeature) theJenkinsfileInTheForkShouldBeValid() error {
+	return p.theFileInTheForkShouldBeAValidJenkinsfile(jenkinsfileName)
+}
+
+func (f *importFeature) weValidateTheJenkinsfileOfPullRequestsBeforeMergingThem() error {
+	policy, err := f.mergePolicy()
+	if err != nil {
+		return err
+	}
+	policy.Validate = func(client *gh.Client, pr *gh.PullRequest) error {
+		return validatePullRequestJenkinsfile(f.Context, client, pr)
+	}
+	return nil
+}
+
+// validatePullRequestJenkinsfile validates the Jenkinsfile of the pull request if it has one
+func validatePullRequestJenkinsfile(c *utils.ScenarioContext, client *gh.Client, pr *gh.PullRequest) error {
+	content, found, err := github.GetPullRequestFile(client, pr, jenkinsfileName)
+	if err != nil || !found {
+		return err
+	}
+	validation, err := validateJenkinsfile(c, content)
+	if err != nil {
+		return err
+	}
+	return validation.AssertValid(c, fmt.Sprintf("the %s of PR #%d", jenkinsfileName, pr.GetNumber()))
+}
+
+func JenkinsfileLinterFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	f := &jenkinsfileFeature{
+		Context: c,
+	}
+
+	c.Step(`^the Jenkinsfile should be valid:$`, f.theJenkinsfileShouldBeValid)
+	c.Step(`^the Jenkinsfile should have an error at line (\d+):$`, f.theJenkinsfileShouldHaveAnErrorAtLine)
+}
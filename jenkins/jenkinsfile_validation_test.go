@@ -0,0 +1,102 @@
+package jenkins
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseJenkinsfileValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		valid       bool
+		declarative bool
+		errors      []JenkinsfileError
+	}{
+		{
+			name:        "valid",
+			output:      "Jenkinsfile successfully validated.\n",
+			valid:       true,
+			declarative: true,
+		},
+		{
+			name:        "scripted",
+			output:      "Errors encountered validating Jenkinsfile:\nJenkinsfile content 'node { sh 'make' }' did not contain the 'pipeline' step\n",
+			valid:       false,
+			declarative: false,
+		},
+		{
+			name: "errors with columns",
+			output: "Errors encountered validating Jenkinsfile:\n" +
+				"WorkflowScript: 3: Expected a stage @ line 3, column 5.\n" +
+				"       stages {\n" +
+				"       ^\n" +
+				"\n" +
+				"WorkflowScript: 7: Undefined section \"foo\" @ line 7, column 3.\n",
+			declarative: true,
+			errors: []JenkinsfileError{
+				{Line: 3, Column: 5, Message: "Expected a stage"},
+				{Line: 7, Column: 3, Message: `Undefined section "foo"`},
+			},
+		},
+		{
+			name:        "error without a column",
+			output:      "Errors encountered validating Jenkinsfile:\nWorkflowScript: 4: Missing required section \"agent\"\n",
+			declarative: true,
+			errors: []JenkinsfileError{
+				{Line: 4, Message: `Missing required section "agent"`},
+			},
+		},
+		{
+			name:        "unrecognised error",
+			output:      "  java.lang.NullPointerException  \n",
+			declarative: true,
+			errors: []JenkinsfileError{
+				{Message: "java.lang.NullPointerException"},
+			},
+		},
+	}
+	for _, test := range tests {
+		actual := parseJenkinsfileValidation(test.output)
+		if actual.Valid != test.valid || actual.Declarative != test.declarative {
+			t.Errorf("%s: Valid = %v, Declarative = %v, expected %v, %v", test.name, actual.Valid, actual.Declarative, test.valid, test.declarative)
+		}
+		if actual.Output != test.output {
+			t.Errorf("%s: Output = %q, expected %q", test.name, actual.Output, test.output)
+		}
+		errors := []JenkinsfileError{}
+		for _, e := range actual.Errors {
+			errors = append(errors, *e)
+		}
+		if len(test.errors) == 0 {
+			test.errors = []JenkinsfileError{}
+		}
+		if !reflect.DeepEqual(errors, test.errors) {
+			t.Errorf("%s: Errors = %+v, expected %+v", test.name, errors, test.errors)
+		}
+	}
+}
+
+func TestJenkinsfileValidationHasErrorAtLine(t *testing.T) {
+	validation := parseJenkinsfileValidation("WorkflowScript: 3: Expected a stage @ line 3, column 5.\n")
+	if !validation.HasErrorAtLine(3) {
+		t.Errorf("HasErrorAtLine(3) should be true for %+v", validation.Errors)
+	}
+	if validation.HasErrorAtLine(4) {
+		t.Errorf("HasErrorAtLine(4) should be false for %+v", validation.Errors)
+	}
+}
+
+func TestJenkinsfileValidationAssertValidScripted(t *testing.T) {
+	validation := parseJenkinsfileValidation("Jenkinsfile content 'node {}' did not contain the 'pipeline' step\n")
+	os.Unsetenv("BDD_SKIP_SCRIPTED_JENKINSFILES")
+	if err := validation.AssertValid(nil, "Jenkinsfile"); err == nil {
+		t.Errorf("AssertValid should fail for a scripted pipeline")
+	}
+	os.Setenv("BDD_SKIP_SCRIPTED_JENKINSFILES", "true")
+	defer os.Unsetenv("BDD_SKIP_SCRIPTED_JENKINSFILES")
+	if err := validation.AssertValid(nil, "Jenkinsfile"); err != nil {
+		t.Errorf("AssertValid should skip a scripted pipeline when enabled but failed: %v", err)
+	}
+}
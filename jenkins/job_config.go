@@ -0,0 +1,47 @@
+package jenkins
+
+import (
+	"fmt"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+// the paths of the branch include pattern of an organisation folder's navigator, newest first
+var branchIncludePaths = []string{
+	"//navigators/*/traits/jenkins.scm.impl.trait.WildcardSCMHeadFilterTrait/includes",
+	"//navigators/*/traits/jenkins.scm.impl.trait.RegexSCMHeadFilterTrait/regex",
+	"//navigators/*/includes",
+}
+
+// GetJobConfig returns the config.xml of the job
+func GetJobConfig(api *utils.JenkinsAPI, path JobPath) ([]byte, error) {
+	data, err := api.Get(path.URLPath() + "/config.xml")
+	if err != nil {
+		return nil, fmt.Errorf("error getting the config of job %s due to %v", path.FullName(), err)
+	}
+	return data, nil
+}
+
+// UpdateJobConfig replaces the config.xml of the job
+func UpdateJobConfig(api *utils.JenkinsAPI, path JobPath, config []byte) error {
+	_, err := api.Post(path.URLPath()+"/config.xml", "application/xml", config)
+	if err != nil {
+		return fmt.Errorf("error updating the config of job %s due to %v", path.FullName(), err)
+	}
+	return nil
+}
+
+// ParameterDefaultPath returns the path of the default value of the parameter in a job config
+func ParameterDefaultPath(parameter string) string {
+	return fmt.Sprintf("//parameterDefinitions/*[name='%s']/defaultValue", parameter)
+}
+
+// BranchIncludePath returns the path of the branch include pattern in the config of an organisation folder
+func BranchIncludePath(config *utils.XMLDocument) (string, error) {
+	for _, path := range branchIncludePaths {
+		if _, err := config.Get(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("error finding a branch include pattern in the navigators of the organisation")
+}
@@ -0,0 +1,191 @@
+package jenkins
+
+import (
+	"fmt"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+// originalJobConfig is the config of a job before the scenario changed it
+type originalJobConfig struct {
+	Path   JobPath
+	Config []byte
+}
+
+type jobConfigFeature struct {
+	Context   *utils.ScenarioContext
+	Originals []*originalJobConfig
+}
+
+// loadConfig returns the parsed config of the job
+func (f *jobConfigFeature) loadConfig(api *utils.JenkinsAPI, jobExpression string) (JobPath, *utils.XMLDocument, error) {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	// other scenarios using the job wait until its original config has been restored at the end of this one
+	f.Context.LockResource("job " + jobPath.FullName())
+	data, err := GetJobConfig(api, jobPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := utils.ParseXMLDocument(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing the config of job %s due to %v", jobPath.FullName(), err)
+	}
+	f.rememberOriginal(jobPath, data)
+	return jobPath, config, nil
+}
+
+// rememberOriginal keeps the first config of each job so it can be restored at the end of the scenario
+func (f *jobConfigFeature) rememberOriginal(jobPath JobPath, data []byte) {
+	for _, original := range f.Originals {
+		if original.Path.FullName() == jobPath.FullName() {
+			return
+		}
+	}
+	if len(f.Originals) == 0 {
+		f.Context.AddCleanup(f.restoreConfigs)
+	}
+	f.Originals = append(f.Originals, &originalJobConfig{
+		Path:   jobPath,
+		Config: data,
+	})
+}
+
+// updateConfig applies the change to the config of the job and uploads it
+func (f *jobConfigFeature) updateConfig(jobExpression string, change func(config *utils.XMLDocument) error) error {
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	jobPath, config, err := f.loadConfig(api, jobExpression)
+	if err != nil {
+		return err
+	}
+	err = change(config)
+	if err != nil {
+		return fmt.Errorf("error changing the config of job %s due to %v", jobPath.FullName(), err)
+	}
+	err = UpdateJobConfig(api, jobPath, config.Bytes())
+	if err != nil {
+		return err
+	}
+	f.Context.LogInfof("updated the config of job %s\n", jobPath.FullName())
+	return nil
+}
+
+func (f *jobConfigFeature) setConfigValue(jobExpression string, path string, value string) error {
+	return f.updateConfig(jobExpression, func(config *utils.XMLDocument) error {
+		_, err := config.Set(path, f.Context.ReplaceVariables(value, nil))
+		return err
+	})
+}
+
+func (f *jobConfigFeature) iSetInTheConfigOfJobTo(path string, jobExpression string, value string) error {
+	return f.setConfigValue(jobExpression, path, value)
+}
+
+func (f *jobConfigFeature) iSetTheDefaultValueOfParameterOfJobTo(parameter string, jobExpression string, value string) error {
+	return f.setConfigValue(jobExpression, ParameterDefaultPath(parameter), value)
+}
+
+func (f *jobConfigFeature) iDisableTheJob(jobExpression string) error {
+	return f.setConfigValue(jobExpression, "/*/disabled", "true")
+}
+
+func (f *jobConfigFeature) iEnableTheJob(jobExpression string) error {
+	return f.setConfigValue(jobExpression, "/*/disabled", "false")
+}
+
+func (f *jobConfigFeature) iSetTheBranchIncludePatternOfOrganisationTo(jobExpression string, pattern string) error {
+	return f.updateConfig(jobExpression, func(config *utils.XMLDocument) error {
+		path, err := BranchIncludePath(config)
+		if err != nil {
+			return err
+		}
+		_, err = config.Set(path, f.Context.ReplaceVariables(pattern, nil))
+		return err
+	})
+}
+
+func (f *jobConfigFeature) theConfigOfJobShouldHave(jobExpression string, path string, expected string) error {
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	jobPath, config, err := f.loadConfig(api, jobExpression)
+	if err != nil {
+		return err
+	}
+	actual, err := config.Get(path)
+	if err != nil {
+		return fmt.Errorf("error checking the config of job %s due to %v", jobPath.FullName(), err)
+	}
+	expected = f.Context.ReplaceVariables(expected, nil)
+	if actual != expected {
+		return fmt.Errorf("the config of job %s has %s = %q but expected %q", jobPath.FullName(), path, actual, expected)
+	}
+	return nil
+}
+
+func (f *jobConfigFeature) theBranchIncludePatternOfOrganisationShouldBe(jobExpression string, expected string) error {
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	jobPath, config, err := f.loadConfig(api, jobExpression)
+	if err != nil {
+		return err
+	}
+	path, err := BranchIncludePath(config)
+	if err != nil {
+		return err
+	}
+	actual, err := config.Get(path)
+	if err != nil {
+		return err
+	}
+	expected = f.Context.ReplaceVariables(expected, nil)
+	if actual != expected {
+		return fmt.Errorf("the branch include pattern of %s is %q but expected %q", jobPath.FullName(), actual, expected)
+	}
+	return nil
+}
+
+// restoreConfigs puts back the original config of every job the scenario changed as a cleanup of the scenario
+func (f *jobConfigFeature) restoreConfigs() {
+	defer func() {
+		f.Originals = nil
+	}()
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		f.Context.LogInfof("WARNING: failed to restore the config of jobs: %v\n", err)
+		return
+	}
+	for i := len(f.Originals) - 1; i >= 0; i-- {
+		original := f.Originals[i]
+		err := UpdateJobConfig(api, original.Path, original.Config)
+		if err != nil {
+			f.Context.LogInfof("WARNING: failed to restore the config of job %s: %v\n", original.Path.FullName(), err)
+			continue
+		}
+		f.Context.LogInfof("restored the config of job %s\n", original.Path.FullName())
+	}
+}
+
+func JobConfigFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	f := &jobConfigFeature{
+		Context: c,
+	}
+
+	c.Step(`^I set "([^"]*)" in the config of job "([^"]*)" to "([^"]*)"$`, f.iSetInTheConfigOfJobTo)
+	c.Step(`^I set the default value of parameter "([^"]*)" of job "([^"]*)" to "([^"]*)"$`, f.iSetTheDefaultValueOfParameterOfJobTo)
+	c.Step(`^I disable the job "([^"]*)"$`, f.iDisableTheJob)
+	c.Step(`^I enable the job "([^"]*)"$`, f.iEnableTheJob)
+	c.Step(`^I set the branch include pattern of organisation "([^"]*)" to "([^"]*)"$`, f.iSetTheBranchIncludePatternOfOrganisationTo)
+	c.Step(`^the config of job "([^"]*)" should have "([^"]*)" = "([^"]*)"$`, f.theConfigOfJobShouldHave)
+	c.Step(`^the branch include pattern of organisation "([^"]*)" should be "([^"]*)"$`, f.theBranchIncludePatternOfOrganisationShouldBe)
+}
@@ -0,0 +1,178 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// JobPath is the path of a job through its folders where each element is the name Jenkins gives the job.
+// Multibranch projects name the job of a branch such as `feature/login` as `feature%2Flogin`
+type JobPath []string
+
+// ParseJobPath parses a job expression such as `GitHub/$GITHUB_USER/my-repo/${DEFAULT_BRANCH}` into a JobPath.
+//
+// Segments are separated by `/`. A segment containing a `/` such as a branch name can be quoted as
+// `my-repo/'feature/login'` or escaped as `my-repo/feature\/login`. Steps take job expressions inside double
+// quotes so segments should be quoted with single quotes in feature files. Variables are replaced inside each
+// segment so their values may contain a `/`. Segments containing a `/` are encoded the way multibranch
+// projects name branch jobs, other segments are used as they are so `feature%2Flogin` also works
+func ParseJobPath(c *utils.ScenarioContext, expression string, variables map[string]string) (JobPath, error) {
+	segments, err := splitJobExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	answer := JobPath{}
+	for _, segment := range segments {
+		name := c.ReplaceVariables(segment, variables)
+		if name == "" {
+			return nil, fmt.Errorf("Invalid job expression %s as it has an empty job name", expression)
+		}
+		if strings.Contains(name, "/") {
+			name = EncodeJobName(name)
+		}
+		answer = append(answer, name)
+	}
+	return answer, nil
+}
+
+// ParseJobFullName returns the JobPath of the fullName of a Jenkins job such as `GitHub/my-repo/feature%2Flogin`
+func ParseJobFullName(fullName string) JobPath {
+	return JobPath(strings.Split(strings.Trim(fullName, "/"), "/"))
+}
+
+// ParseJobURL returns the JobPath of a job URL such as `http://jenkins/job/GitHub/job/my-repo/job/feature%252Flogin/`
+func ParseJobURL(jobURL string) (JobPath, error) {
+	u, err := url.Parse(jobURL)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse job URL %s due to %v", jobURL, err)
+	}
+	answer := JobPath{}
+	parts := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] != "job" {
+			continue
+		}
+		i++
+		name, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode job name %s in URL %s due to %v", parts[i], jobURL, err)
+		}
+		answer = append(answer, name)
+	}
+	if len(answer) == 0 {
+		return nil, fmt.Errorf("No job found in URL %s", jobURL)
+	}
+	return answer, nil
+}
+
+// EncodeJobName returns the name a multibranch project gives the job of a branch
+func EncodeJobName(branch string) string {
+	return strings.Replace(strings.Replace(branch, "%", "%25", -1), "/", "%2F", -1)
+}
+
+// FullName returns the Jenkins fullName of the job
+func (p JobPath) FullName() string {
+	return strings.Join(p, "/")
+}
+
+// URLPath returns the path of the job relative to the Jenkins URL such as `/job/my-repo/job/feature%252Flogin`
+func (p JobPath) URLPath() string {
+	return gojenkins.FullJobPath(p.escapedNames()...)
+}
+
+// Name returns the name of the job without its folders
+func (p JobPath) Name() string {
+	if len(p) == 0 {
+		return ""
+	}
+	return p[len(p)-1]
+}
+
+// Parent returns the path of the folder containing the job
+func (p JobPath) Parent() JobPath {
+	if len(p) == 0 {
+		return p
+	}
+	return p[:len(p)-1]
+}
+
+// Child returns the path of the job with the name inside this job
+func (p JobPath) Child(name string) JobPath {
+	answer := append(JobPath{}, p...)
+	return append(answer, name)
+}
+
+// String returns the job expression which parses back into this path
+func (p JobPath) String() string {
+	segments := []string{}
+	for _, name := range p {
+		segments = append(segments, escapeJobSegment(name))
+	}
+	return strings.Join(segments, "/")
+}
+
+func (p JobPath) escapedNames() []string {
+	answer := []string{}
+	for _, name := range p {
+		answer = append(answer, url.PathEscape(name))
+	}
+	return answer
+}
+
+// GetJobByPath returns the job at the path
+func GetJobByPath(jenkins *gojenkins.Jenkins, path JobPath) (gojenkins.Job, error) {
+	return jenkins.GetJobByPath(path.escapedNames()...)
+}
+
+// splitJobExpression splits the expression on `/` taking account of quoted segments and escaped characters
+func splitJobExpression(expression string) ([]string, error) {
+	answer := []string{}
+	buffer := &strings.Builder{}
+	var quote rune
+	escaped := false
+	for _, ch := range strings.Trim(expression, "/") {
+		switch {
+		case escaped:
+			buffer.WriteRune(ch)
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			} else {
+				buffer.WriteRune(ch)
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '/':
+			answer = append(answer, buffer.String())
+			buffer.Reset()
+		default:
+			buffer.WriteRune(ch)
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("Invalid job expression %s as it ends with an escape character", expression)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("Invalid job expression %s as it has an unterminated %c quote", expression, quote)
+	}
+	return append(answer, buffer.String()), nil
+}
+
+func escapeJobSegment(name string) string {
+	buffer := &strings.Builder{}
+	for _, ch := range name {
+		switch ch {
+		case '\\', '"', '\'', '/':
+			buffer.WriteRune('\\')
+		}
+		buffer.WriteRune(ch)
+	}
+	return buffer.String()
+}
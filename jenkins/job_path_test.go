@@ -0,0 +1,90 @@
+package jenkins
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJobPath(t *testing.T) {
+	variables := map[string]string{
+		"BRANCH": "feature/login",
+		"REPO":   "my-repo",
+	}
+	tests := []struct {
+		expression string
+		expected   JobPath
+		urlPath    string
+	}{
+		{"fabric8-import", JobPath{"fabric8-import"}, "/job/fabric8-import"},
+		{"/GitHub/my-repo/master/", JobPath{"GitHub", "my-repo", "master"}, "/job/GitHub/job/my-repo/job/master"},
+		{"my-repo/'feature/login'", JobPath{"my-repo", "feature%2Flogin"}, "/job/my-repo/job/feature%252Flogin"},
+		{`my-repo/feature\/login`, JobPath{"my-repo", "feature%2Flogin"}, "/job/my-repo/job/feature%252Flogin"},
+		{"my-repo/feature%2Flogin", JobPath{"my-repo", "feature%2Flogin"}, "/job/my-repo/job/feature%252Flogin"},
+		{"${REPO}/${BRANCH}", JobPath{"my-repo", "feature%2Flogin"}, "/job/my-repo/job/feature%252Flogin"},
+		{"my-repo/'100%/done'", JobPath{"my-repo", "100%25%2Fdone"}, "/job/my-repo/job/100%2525%252Fdone"},
+		{"my-repo/'it''s'", JobPath{"my-repo", "its"}, "/job/my-repo/job/its"},
+		{`my-repo/it\'s`, JobPath{"my-repo", "it's"}, "/job/my-repo/job/it%27s"},
+	}
+	for _, test := range tests {
+		actual, err := ParseJobPath(nil, test.expression, variables)
+		if err != nil {
+			t.Errorf("ParseJobPath(%q) failed: %v", test.expression, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("ParseJobPath(%q) = %#v, expected %#v", test.expression, actual, test.expected)
+		}
+		if actual.URLPath() != test.urlPath {
+			t.Errorf("ParseJobPath(%q).URLPath() = %q, expected %q", test.expression, actual.URLPath(), test.urlPath)
+		}
+	}
+}
+
+func TestParseJobPathErrors(t *testing.T) {
+	for _, expression := range []string{"", "my-repo//master", `my-repo\`, "my-repo/'feature/login"} {
+		_, err := ParseJobPath(nil, expression, nil)
+		if err == nil {
+			t.Errorf("ParseJobPath(%q) should have failed", expression)
+		}
+	}
+}
+
+func TestJobPathRoundTrips(t *testing.T) {
+	paths := []JobPath{
+		{"fabric8-import"},
+		{"GitHub", "my-repo", "master"},
+		{"GitHub", "my-repo", "feature%2Flogin"},
+		{"GitHub", "my-repo", "100%25%2Fdone"},
+		{"folder", "it's \"quoted\"", `back\slash`},
+		{"folder", "with space"},
+	}
+	for _, path := range paths {
+		parsed, err := ParseJobPath(nil, path.String(), nil)
+		if err != nil {
+			t.Errorf("ParseJobPath(%q) failed: %v", path.String(), err)
+		} else if !reflect.DeepEqual(parsed, path) {
+			t.Errorf("ParseJobPath(%q) = %#v, expected %#v", path.String(), parsed, path)
+		}
+
+		jobURL := "http://jenkins.example.com" + path.URLPath() + "/"
+		fromURL, err := ParseJobURL(jobURL)
+		if err != nil {
+			t.Errorf("ParseJobURL(%q) failed: %v", jobURL, err)
+		} else if !reflect.DeepEqual(fromURL, path) {
+			t.Errorf("ParseJobURL(%q) = %#v, expected %#v", jobURL, fromURL, path)
+		}
+
+		if fullName := ParseJobFullName(path.FullName()); !reflect.DeepEqual(fullName, path) {
+			t.Errorf("ParseJobFullName(%q) = %#v, expected %#v", path.FullName(), fullName, path)
+		}
+	}
+}
+
+func TestParseJobURLErrors(t *testing.T) {
+	for _, jobURL := range []string{"http://jenkins.example.com/", "http://jenkins.example.com/view/all/", "http://jenkins.example.com/job/bad%zz/"} {
+		_, err := ParseJobURL(jobURL)
+		if err == nil {
+			t.Errorf("ParseJobURL(%q) should have failed", jobURL)
+		}
+	}
+}
@@ -9,19 +9,29 @@ import (
 )
 
 type mutibranchFeature struct {
-	parent string
-	name   string
-	branch string
-	job    gojenkins.Job
-	client gojenkins.Jenkins
+	Context *utils.ScenarioContext
+	parent  string
+	name    string
+	branch  string
+	job     gojenkins.Job
+	client  gojenkins.Jenkins
 }
 
 func (m *mutibranchFeature) organisationJobContainsAJob(orgJobName, multibranchJobName string) error {
-	jenkins, err := utils.GetJenkinsClient()
+	orgPath, err := ParseJobPath(m.Context, orgJobName, nil)
+	if err != nil {
+		return err
+	}
+	multibranchPath, err := ParseJobPath(m.Context, multibranchJobName, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(m.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
-	job, err := jenkins.GetMultiBranchJob(orgJobName, multibranchJobName, "master")
+	jobPath := append(append(orgPath, multibranchPath...), "master")
+	job, err := GetJobByPath(jenkins, jobPath)
 
 	if err != nil {
 		return fmt.Errorf("error finding multibranch job %s in organisation job %s ", multibranchJobName, orgJobName)
@@ -36,38 +46,38 @@ func (m *mutibranchFeature) iTriggerTheMultibranchJob(multibranchJobName string)
 	if m.name != multibranchJobName {
 		return fmt.Errorf("error matching multi branch Job %s with previously configured job %s", multibranchJobName, m.name)
 	}
-	jenkins, err := utils.GetJenkinsClient()
+	jenkins, err := utils.GetJenkinsClient(m.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
-	utils.LogInfof("Triggering Job: %s\n", m.job.Url)
-	err = jenkins.Build(m.job, nil)
+	m.Context.LogInfof("Triggering Job: %s\n", m.job.Url)
+	build, err := TriggerAndWaitForBuildToStart(m.Context, jenkins, m.job, m.Context.Timeout(utils.TimeoutBuildStart))
 	if err != nil {
-		return fmt.Errorf("error triggering job %s %v", m.job.FullName, err)
+		return err
 	}
-	return nil
+	return getCurrentBuild(m.Context).Set(m.job, build.Number)
 }
 
 func (m *mutibranchFeature) theJobIsSuccessful(arg1 string) error {
-	jenkins, err := utils.GetJenkinsClient()
+	jenkins, err := utils.GetJenkinsClient(m.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
 
 	// wait for build to finish
-	err = utils.RetryAfter(200, func() error {
+	err = m.Context.PollPhase(utils.TimeoutBuild, 5*time.Second, m.Context.Timeout(utils.TimeoutBuild), fmt.Sprintf("last build of job %s to finish", m.job.FullName), func() (bool, error) {
 
 		// wait for build to start
 		build, err := jenkins.GetLastBuild(m.job)
 		if err != nil {
-			return fmt.Errorf("error getting last build for job %s %v", m.job.FullName, err)
+			m.Context.LogInfof("error getting last build for job %s %v\n", m.job.FullName, err)
+			return false, nil
 		}
-
-		if build.Result == "" {
-			return fmt.Errorf("build still running")
-		}
-		return nil
-	}, time.Second*5)
+		return build.Result != "", nil
+	})
+	if err != nil {
+		return err
+	}
 	// check result
 	build, err := jenkins.GetLastBuild(m.job)
 	if err != nil {
@@ -82,9 +92,12 @@ func (m *mutibranchFeature) theJobIsSuccessful(arg1 string) error {
 }
 
 func FeatureMultiBranchContext(s *godog.Suite) {
-	m := &mutibranchFeature{}
+	c := utils.SuiteContext(s)
+	m := &mutibranchFeature{
+		Context: c,
+	}
 
-	s.Step(`^organisation job "([^"]*)" contains a "([^"]*)" job$`, m.organisationJobContainsAJob)
-	s.Step(`^I trigger the multibranch job "([^"]*)"$`, m.iTriggerTheMultibranchJob)
-	s.Step(`^the job "([^"]*)" is successful$`, m.theJobIsSuccessful)
+	c.Step(`^organisation job "([^"]*)" contains a "([^"]*)" job$`, m.organisationJobContainsAJob)
+	c.Step(`^I trigger the multibranch job "([^"]*)"$`, m.iTriggerTheMultibranchJob)
+	c.Step(`^the job "([^"]*)" is successful$`, m.theJobIsSuccessful)
 }
@@ -0,0 +1,133 @@
+package jenkins
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+// sinkFeature has the steps which check the requests the notification sink received
+type sinkFeature struct {
+	Context *utils.ScenarioContext
+}
+
+// sinkScenario returns the running notification sink and the ID of the running scenario
+func (f *sinkFeature) sinkScenario() (*utils.NotificationSink, string, error) {
+	sink := utils.GetNotificationSink()
+	if sink == nil {
+		return nil, "", fmt.Errorf("the notification sink is not running")
+	}
+	scenario := f.Context.Scenario()
+	if scenario == nil {
+		return nil, "", fmt.Errorf("no current scenario for the notification sink")
+	}
+	return sink, scenario.ID, nil
+}
+
+// sinkRequestMatcher returns a function matching requests with the method and path
+func (f *sinkFeature) sinkRequestMatcher(method string, path string) func(*utils.SinkRequest) bool {
+	method = strings.ToUpper(method)
+	path = f.Context.ReplaceVariables(path, nil)
+	return func(r *utils.SinkRequest) bool {
+		return r.Method == method && r.Path == path
+	}
+}
+
+func (f *sinkFeature) waitForSinkRequest(method string, path string, field string, expected string, timeout time.Duration) error {
+	sink, scenarioID, err := f.sinkScenario()
+	if err != nil {
+		return err
+	}
+	matches := f.sinkRequestMatcher(method, path)
+	description := fmt.Sprintf("the sink to receive a %s to %s", method, path)
+	if field != "" {
+		expected = f.Context.ReplaceVariables(expected, nil)
+		description += fmt.Sprintf(" with JSON field %s = %s", field, expected)
+		requestMatches := matches
+		matches = func(r *utils.SinkRequest) bool {
+			if !requestMatches(r) {
+				return false
+			}
+			actual, err := r.JSONField(field)
+			return err == nil && actual == expected
+		}
+	}
+	request, err := sink.WaitForRequest(f.Context, scenarioID, description, timeout, matches)
+	if err != nil {
+		return err
+	}
+	f.Context.LogInfof("the sink received %s\n", request)
+	return nil
+}
+
+func (f *sinkFeature) theSinkShouldHaveReceivedA(method string, path string) error {
+	return f.waitForSinkRequest(method, path, "", "", f.Context.Timeout(utils.TimeoutSink))
+}
+
+func (f *sinkFeature) theSinkShouldHaveReceivedAWithin(method string, path string, amount int, unit string) error {
+	timeout, err := utils.ParseDuration(amount, unit)
+	if err != nil {
+		return err
+	}
+	return f.waitForSinkRequest(method, path, "", "", timeout)
+}
+
+func (f *sinkFeature) theSinkShouldHaveReceivedAWithJSONField(method string, path string, field string, expected string) error {
+	return f.waitForSinkRequest(method, path, field, expected, f.Context.Timeout(utils.TimeoutSink))
+}
+
+func (f *sinkFeature) theSinkShouldHaveReceivedAWithJSONFieldWithin(method string, path string, field string, expected string, amount int, unit string) error {
+	timeout, err := utils.ParseDuration(amount, unit)
+	if err != nil {
+		return err
+	}
+	return f.waitForSinkRequest(method, path, field, expected, timeout)
+}
+
+func (f *sinkFeature) theSinkShouldNotHaveReceivedA(method string, path string) error {
+	sink, scenarioID, err := f.sinkScenario()
+	if err != nil {
+		return err
+	}
+	matches := f.sinkRequestMatcher(method, path)
+	for _, request := range sink.Requests(scenarioID) {
+		if matches(request) {
+			return fmt.Errorf("the sink received %s at %s", request, request.Time.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+func NotificationSinkFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	f := &sinkFeature{
+		Context: c,
+	}
+
+	// with concurrency each feature runs in its own suite so the sink is shared until the last suite stops it
+	started := false
+	s.BeforeSuite(func() {
+		err := utils.StartNotificationSink()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+			return
+		}
+		started = true
+	})
+	s.AfterSuite(func() {
+		if started {
+			utils.StopNotificationSink()
+			started = false
+		}
+	})
+
+	c.Step(`^the sink should have received a (\w+) to "([^"]*)"$`, f.theSinkShouldHaveReceivedA)
+	c.Step(`^the sink should have received a (\w+) to "([^"]*)" within (\d+) (seconds?|minutes?)$`, f.theSinkShouldHaveReceivedAWithin)
+	c.Step(`^the sink should have received a (\w+) to "([^"]*)" with JSON field "([^"]*)" = "([^"]*)"$`, f.theSinkShouldHaveReceivedAWithJSONField)
+	c.Step(`^the sink should have received a (\w+) to "([^"]*)" with JSON field "([^"]*)" = "([^"]*)" within (\d+) (seconds?|minutes?)$`, f.theSinkShouldHaveReceivedAWithJSONFieldWithin)
+	c.Step(`^the sink should not have received a (\w+) to "([^"]*)"$`, f.theSinkShouldNotHaveReceivedA)
+}
@@ -6,21 +6,28 @@ import (
 	"github.com/fabric8-jenkins/godog-jenkins/utils"
 )
 
-func thereAreNoJobsCalled(jobName string) error {
-	jenkins, err := utils.GetJenkinsClient()
+func (f *jobFeature) thereAreNoJobsCalled(jobExpression string) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
 
-	job, err := jenkins.GetJob(jobName)
+	exists, err := JobExists(jenkins, jobPath)
 	if err != nil {
-		return nil
+		return err
 	}
-	return fmt.Errorf("error found existing job %s", job.Name)
+	if exists {
+		return fmt.Errorf("error found existing job %s", jobPath.FullName())
+	}
+	return nil
 }
 
-func iImportTheGitHubOrganisation(jobName string) error {
-	jenkins, err := utils.GetJenkinsClient()
+func (f *jobFeature) iImportTheGitHubOrganisation(jobName string) error {
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
@@ -34,44 +41,61 @@ func iImportTheGitHubOrganisation(jobName string) error {
 	if err != nil {
 		return fmt.Errorf("error creating organisation Job %v", err)
 	}
+	f.Context.RecordEvent(&utils.Event{
+		Type: utils.EventJobCreated,
+		Job:  jobName,
+	})
 	return nil
 }
 
-func thereShouldBeAJobAndMoreThanMultibranchJob(jobName string, numberOfMultiBranchProjects int) error {
-	jenkins, err := utils.GetJenkinsClient()
+func (f *jobFeature) thereShouldBeAJobAndMoreThanMultibranchJob(jobExpression string, numberOfMultiBranchProjects int) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
 
-	job, err := jenkins.GetJob(jobName)
+	_, err = GetJobByPath(jenkins, jobPath)
 	if err != nil {
-		return fmt.Errorf("error found existing job %s ", job.Name)
+		return fmt.Errorf("error finding existing job %s %v", jobPath.FullName(), err)
 	}
 
 	return nil
 }
 
-func triggerJob(jobName string) error {
-	jenkins, err := utils.GetJenkinsClient()
+func (f *jobFeature) triggerJob(jobExpression string) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client  %v", err)
 	}
 
-	job, err := jenkins.GetJob(jobName)
+	job, err := GetJobByPath(jenkins, jobPath)
 	if err != nil {
-		return fmt.Errorf("error finding existing job %s %v", jobName, err)
+		return fmt.Errorf("error finding existing job %s %v", jobPath.FullName(), err)
 	}
 
-	err = jenkins.Build(job, nil)
+	build, err := TriggerAndWaitForBuildToStart(f.Context, jenkins, job, f.Context.Timeout(utils.TimeoutBuildStart))
 	if err != nil {
-		return fmt.Errorf("error triggering build %s %v", jobName, err)
+		return err
 	}
-	return nil
+	return getCurrentBuild(f.Context).Set(job, build.Number)
 }
 
 func ImportOrganisationFeatureContext(s *godog.Suite) {
-	s.Step(`^there are no jobs called "([^"]*)"$`, thereAreNoJobsCalled)
-	s.Step(`^trigger job "([^"]*)"$`, triggerJob)
-	s.Step(`^I import the "([^"]*)" GitHub organisation$`, iImportTheGitHubOrganisation)
-	s.Step(`^there should be a "([^"]*)" job and more than (\d+) multibranch job$`, thereShouldBeAJobAndMoreThanMultibranchJob)
+	c := utils.SuiteContext(s)
+	f := &jobFeature{
+		Context: c,
+	}
+
+	c.Step(`^there are no jobs called "([^"]*)"$`, f.thereAreNoJobsCalled)
+	c.Step(`^trigger job "([^"]*)"$`, f.triggerJob)
+	c.Step(`^I import the "([^"]*)" GitHub organisation$`, f.iImportTheGitHubOrganisation)
+	c.Step(`^there should be a "([^"]*)" job and more than (\d+) multibranch job$`, f.thereShouldBeAJobAndMoreThanMultibranchJob)
 }
@@ -2,35 +2,66 @@ package jenkins
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
 	"github.com/DATA-DOG/godog"
 	"github.com/fabric8-jenkins/godog-jenkins/utils"
 )
 
-func thenWaitToCheckTheOrganisationScanForIsSuccessful(jobName string) error {
-	jenkins, err := utils.GetJenkinsClient()
+func (f *jobFeature) thenWaitToCheckTheOrganisationScanForIsSuccessful(jobExpression string) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
 	if err != nil {
 		return fmt.Errorf("error getting a Jenkins client %v", err)
 	}
-
-	job, err := jenkins.GetJob(jobName)
+	_, err = GetJobByPath(jenkins, jobPath)
 	if err != nil {
-		return fmt.Errorf("error found existing job %s ", job.Name)
+		return fmt.Errorf("error finding existing job %s %v", jobPath.FullName(), err)
 	}
-
-	result, err := jenkins.GetOrganizationScanResult(200, job)
-
+	api, err := getJenkinsAPI(f.Context)
 	if err != nil {
-		return fmt.Errorf("error getting org scan result %v", err)
+		return err
 	}
 
+	// the log of the scan ends with its result once it has finished
+	result := ""
+	fn := func() (bool, error) {
+		data, err := api.Get(jobPath.URLPath() + "/computation/consoleText")
+		if err != nil {
+			if utils.IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("error getting the scan log of %s due to %v", jobPath.FullName(), err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		lastLine := strings.TrimSpace(lines[len(lines)-1])
+		if strings.HasPrefix(lastLine, "Finished: ") {
+			result = strings.TrimPrefix(lastLine, "Finished: ")
+			return true, nil
+		}
+		return false, nil
+	}
+	err = f.Context.PollPhase(utils.TimeoutScan, 5*time.Second, f.Context.Timeout(utils.TimeoutScan), fmt.Sprintf("the scan of %s to finish", jobPath.FullName()), fn)
+	if err != nil {
+		return err
+	}
 	if result == "SUCCESS" {
 		return nil
 	}
-	return fmt.Errorf("error the %s org scan result was %s", jobName, result)
+	return fmt.Errorf("error the %s org scan result was %s", jobPath.FullName(), result)
 }
 
 func FeatureTriggerContext(s *godog.Suite) {
-	s.Step(`^there is a "([^"]*)" job$`, thereIsAJobCalled)
-	s.Step(`^I trigger the "([^"]*)" job$`, triggerJob)
-	s.Step(`^then wait to check the organisation scan for "([^"]*)" is successful$`, thenWaitToCheckTheOrganisationScanForIsSuccessful)
+	c := utils.SuiteContext(s)
+	f := &jobFeature{
+		Context: c,
+	}
+
+	c.Step(`^there is a "([^"]*)" job$`, f.thereIsAJobCalled)
+	c.Step(`^I trigger the "([^"]*)" job$`, f.triggerJob)
+	c.Step(`^then wait to check the organisation scan for "([^"]*)" is successful$`, f.thenWaitToCheckTheOrganisationScanForIsSuccessful)
 }
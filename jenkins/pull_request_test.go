@@ -0,0 +1,233 @@
+package jenkins
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/fabric8-jenkins/godog-jenkins/github"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/fabric8-jenkins/golang-jenkins"
+
+	gh "github.com/google/go-github/github"
+)
+
+type pullRequestFeature struct {
+	Context          *utils.ScenarioContext
+	Forker           *github.ForkFeature
+	GitHubClient     *gh.Client
+	Jenkins          *gojenkins.Jenkins
+	UpstreamRepoName string
+	PullRequest      *gh.PullRequest
+	PullRequestJob   gojenkins.Job
+
+	// WebhookShas are the last commits sent in push webhooks by branch
+	WebhookShas map[string]string
+	// WebhookTime is when Jenkins received the last webhook
+	WebhookTime time.Time
+}
+
+func (p *pullRequestFeature) weHaveACleanForkOf(originalRepoName string) error {
+	p.Forker = &github.ForkFeature{
+		Context:      p.Context,
+		GitCommander: github.CreateGitCommander(p.Context),
+	}
+	_, err := p.Forker.ForkToUsersRepo(originalRepoName)
+	if err != nil {
+		return err
+	}
+	p.UpstreamRepoName = originalRepoName
+	p.WebhookShas = map[string]string{}
+
+	p.GitHubClient, err = github.CreateGitHubClient(p.Context)
+	if err != nil {
+		return err
+	}
+	p.Jenkins, err = utils.GetJenkinsClient(p.Context)
+	if err != nil {
+		return fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	return nil
+}
+
+// fork returns the fork of the scenario or an error if no fork has been created yet
+func (p *pullRequestFeature) fork() (*github.ForkFeature, error) {
+	if p.Forker == nil {
+		return nil, fmt.Errorf("No fork has been created yet")
+	}
+	return p.Forker, nil
+}
+
+func (p *pullRequestFeature) weCreateBranchInTheForkChanging(branch string, fileName string) error {
+	fork, err := p.fork()
+	if err != nil {
+		return err
+	}
+	branch = p.Context.ReplaceVariables(branch, fork.Variables())
+	err = fork.CreateBranch(branch)
+	if err != nil {
+		return err
+	}
+	err = fork.AppendToFile(fileName, fmt.Sprintf("\nchanged by godog at %s\n", time.Now().UTC().Format(time.RFC3339)))
+	if err != nil {
+		return err
+	}
+	err = fork.Commit("godog changing " + fileName)
+	if err != nil {
+		return err
+	}
+	_, err = fork.PushBranch(branch)
+	return err
+}
+
+func (p *pullRequestFeature) weOpenAPullRequestFromBranchInTheFork(branch string) error {
+	fork, err := p.fork()
+	if err != nil {
+		return err
+	}
+	forkRepo, err := github.ParseUserRepositoryName(fork.ForkedRepoName)
+	if err != nil {
+		return err
+	}
+	return p.openPullRequest(forkRepo, p.Context.ReplaceVariables(branch, fork.Variables()))
+}
+
+func (p *pullRequestFeature) weOpenAPullRequestFromBranchInTheForkAgainstTheUpstreamRepository(branch string) error {
+	fork, err := p.fork()
+	if err != nil {
+		return err
+	}
+	upstreamRepo, err := github.ParseUserRepositoryName(p.UpstreamRepoName)
+	if err != nil {
+		return err
+	}
+	forkRepo, err := github.ParseUserRepositoryName(fork.ForkedRepoName)
+	if err != nil {
+		return err
+	}
+	return p.openPullRequest(upstreamRepo, forkRepo.Organisation+":"+p.Context.ReplaceVariables(branch, fork.Variables()))
+}
+
+func (p *pullRequestFeature) openPullRequest(repo *github.UserRepositoryName, head string) error {
+	if p.Forker.PushedSha == "" {
+		return fmt.Errorf("No branch has been pushed to the fork yet")
+	}
+	title := fmt.Sprintf("godog test of branch %s", p.Forker.PushedBranch)
+	pr, err := github.CreatePullRequest(p.Context, p.GitHubClient, repo, title, head, p.Forker.ResetBranch())
+	if err != nil {
+		return err
+	}
+	p.PullRequest = pr
+	return nil
+}
+
+func (p *pullRequestFeature) thePullRequestShouldBeDiscoveredBy(jobExpression string) error {
+	pr := p.PullRequest
+	if pr == nil || pr.Number == nil {
+		return fmt.Errorf("No pull request has been opened yet")
+	}
+	jenkins := p.Jenkins
+	variables := p.Forker.Variables()
+	job, err := waitForJobByExpression(p.Context, jenkins, jobExpression, variables, p.Context.Timeout(utils.TimeoutBuildCreated))
+	if err != nil {
+		return err
+	}
+
+	// lets trigger a scan of the multibranch project in case the webhook is not configured
+	err = jenkins.Build(job, nil)
+	if err != nil && !Is404(err) {
+		return fmt.Errorf("error triggering scan of %s due to %v", job.Url, err)
+	}
+	p.Context.RecordEvent(&utils.Event{
+		Type: utils.EventBuildTriggered,
+		Job:  jobFullName(job),
+	})
+
+	prJobExpression := jobExpression + "/PR-" + strconv.Itoa(*pr.Number)
+	p.PullRequestJob, err = waitForJobByExpression(p.Context, jenkins, prJobExpression, variables, p.Context.Timeout(utils.TimeoutBuildCreated))
+	return err
+}
+
+func (p *pullRequestFeature) thePullRequestBuildShouldComplete(expectedResult string) error {
+	job := p.PullRequestJob
+	if job.Url == "" {
+		return fmt.Errorf("No pull request job has been discovered yet")
+	}
+	build, err := WaitForLastBuildToFinish(p.Context, p.Jenkins, job, p.Context.Timeout(utils.TimeoutBuildCreated), p.Context.Timeout(utils.TimeoutBuild))
+	if err != nil {
+		return err
+	}
+	return AssertBuildResult(p.Context, build, job.Url, expectedResult)
+}
+
+func (p *pullRequestFeature) thePullRequestCommitShouldHaveStatusWithin(statusContext string, expectedState string, amount int, unit string) error {
+	timeout, err := utils.ParseDuration(amount, unit)
+	if err != nil {
+		return err
+	}
+	repo, err := p.pullRequestBaseRepository()
+	if err != nil {
+		return err
+	}
+	return github.WaitForCommitStatus(p.Context, p.GitHubClient, repo, p.headSha(), statusContext, expectedState, timeout)
+}
+
+func (p *pullRequestFeature) thePullRequestCommitShouldHaveCheckRunWithin(name string, expectedConclusion string, amount int, unit string) error {
+	timeout, err := utils.ParseDuration(amount, unit)
+	if err != nil {
+		return err
+	}
+	repo, err := p.pullRequestBaseRepository()
+	if err != nil {
+		return err
+	}
+	return github.WaitForCheckRun(p.Context, p.GitHubClient, repo, p.headSha(), name, expectedConclusion, timeout)
+}
+
+// headSha returns the last commit pushed to the fork which is the head of the pull request
+func (p *pullRequestFeature) headSha() string {
+	if p.Forker == nil {
+		return ""
+	}
+	return p.Forker.PushedSha
+}
+
+// pullRequestBaseRepository returns the repository the pull request was opened against
+func (p *pullRequestFeature) pullRequestBaseRepository() (*github.UserRepositoryName, error) {
+	pr := p.PullRequest
+	if pr == nil || pr.Base == nil || pr.Base.Repo == nil || pr.Base.Repo.FullName == nil {
+		return nil, fmt.Errorf("No pull request has been opened yet")
+	}
+	return github.ParseUserRepositoryName(*pr.Base.Repo.FullName)
+}
+
+func FeaturePullRequestContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	p := &pullRequestFeature{
+		Context: c,
+	}
+
+	s.BeforeScenario(func(interface{}) {
+		p.Forker = nil
+		p.PullRequest = nil
+		p.PullRequestJob = gojenkins.Job{}
+		p.WebhookShas = map[string]string{}
+		p.WebhookTime = time.Time{}
+	})
+
+	c.Step(`^we have a clean fork of "([^"]*)"$`, p.weHaveACleanForkOf)
+	c.Step(`^we create branch "([^"]*)" in the fork changing "([^"]*)"$`, p.weCreateBranchInTheForkChanging)
+	github.ForkGitSteps(c, p.fork)
+	c.Step(`^we open a pull request from branch "([^"]*)" in the fork$`, p.weOpenAPullRequestFromBranchInTheFork)
+	c.Step(`^we open a pull request from branch "([^"]*)" in the fork against the upstream repository$`, p.weOpenAPullRequestFromBranchInTheForkAgainstTheUpstreamRepository)
+	c.Step(`^the pull request should be discovered by "([^"]*)"$`, p.thePullRequestShouldBeDiscoveredBy)
+	c.Step(`^the pull request build should complete with result "([^"]*)"$`, p.thePullRequestBuildShouldComplete)
+	c.Step(`^the pull request commit should have status "([^"]*)" = (\w+) within (\d+) (seconds?|minutes?)$`, p.thePullRequestCommitShouldHaveStatusWithin)
+	c.Step(`^the pull request commit should have check run "([^"]*)" = (\w+) within (\d+) (seconds?|minutes?)$`, p.thePullRequestCommitShouldHaveCheckRunWithin)
+	c.Step(`^GitHub sends a push webhook for branch "([^"]*)" of the fork$`, p.gitHubSendsAPushWebhookForBranchOfTheFork)
+	c.Step(`^GitHub sends a pull request webhook with action "([^"]*)"$`, p.gitHubSendsAPullRequestWebhookWithAction)
+	c.Step(`^the job "([^"]*)" should start a build within (\d+) (seconds?|minutes?)$`, p.theJobShouldStartABuildWithin)
+	c.Step(`^the Jenkinsfile in the fork should be valid$`, p.theJenkinsfileInTheForkShouldBeValid)
+	c.Step(`^the file "([^"]*)" in the fork should be a valid Jenkinsfile$`, p.theFileInTheForkShouldBeAValidJenkinsfile)
+}
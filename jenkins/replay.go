@@ -0,0 +1,121 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// the form field of the main script on the replay page
+const replayMainScriptField = "mainScript"
+
+// replayScriptPattern matches the editors of the main and loaded scripts on the replay page of a build
+var replayScriptPattern = regexp.MustCompile(`(?s)<textarea[^>]*\bname="_\.([^"]+)"[^>]*>(.*?)</textarea>`)
+
+// ReplayScripts are the scripts of a Pipeline build which can be changed when it is replayed
+type ReplayScripts struct {
+	MainScript string
+	// LoadedScripts are the scripts loaded by the build such as `Script1` or library classes by their form field
+	LoadedScripts map[string]string
+}
+
+// replayPath returns the path of the replay action of the build
+func replayPath(path JobPath, buildNumber int) string {
+	return buildPath(path, buildNumber) + "/replay"
+}
+
+// GetReplayScripts returns the scripts that the build ran which are the starting point for replaying it
+func GetReplayScripts(api *utils.JenkinsAPI, path JobPath, buildNumber int) (*ReplayScripts, error) {
+	data, err := api.Get(replayPath(path, buildNumber) + "/")
+	if err != nil {
+		return nil, fmt.Errorf("error getting the scripts of job %s build #%d to replay due to %v", path.FullName(), buildNumber, err)
+	}
+	answer := &ReplayScripts{
+		LoadedScripts: map[string]string{},
+	}
+	found := false
+	for _, match := range replayScriptPattern.FindAllStringSubmatch(string(data), -1) {
+		// browsers ignore a newline at the start of a textarea
+		content := strings.TrimPrefix(html.UnescapeString(match[2]), "\n")
+		if match[1] == replayMainScriptField {
+			answer.MainScript = content
+			found = true
+		} else {
+			answer.LoadedScripts[match[1]] = content
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("error job %s build #%d cannot be replayed as it has no main script", path.FullName(), buildNumber)
+	}
+	return answer, nil
+}
+
+// SetLoadedScript replaces the loaded script with the name such as `Script1` or `org.example.Helper`
+func (s *ReplayScripts) SetLoadedScript(name string, script string) error {
+	field := strings.Replace(name, ".", "_", -1)
+	if _, ok := s.LoadedScripts[field]; !ok {
+		names := []string{}
+		for n := range s.LoadedScripts {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("the build did not load the script %s. It loaded: %s", name, strings.Join(names, ", "))
+	}
+	s.LoadedScripts[field] = script
+	return nil
+}
+
+// ReplayBuild starts a new build of the job which replays the build with the scripts
+func ReplayBuild(c *utils.ScenarioContext, api *utils.JenkinsAPI, path JobPath, buildNumber int, scripts *ReplayScripts) error {
+	fields := map[string]string{
+		replayMainScriptField: scripts.MainScript,
+	}
+	for name, script := range scripts.LoadedScripts {
+		fields[name] = script
+	}
+	form, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("error marshalling the replay form due to %v", err)
+	}
+	values := url.Values{
+		"json": {string(form)},
+	}
+	for name, script := range fields {
+		values.Set("_."+name, script)
+	}
+	_, err = api.PostForm(replayPath(path, buildNumber)+"/run", values)
+	if err != nil {
+		return fmt.Errorf("error replaying job %s build #%d due to %v", path.FullName(), buildNumber, err)
+	}
+	c.LogInfof("replaying job %s build #%d\n", path.FullName(), buildNumber)
+	return nil
+}
+
+// ReplayAndWaitForBuildToStart replays the build with the scripts and waits for the new Build to start or returns an error
+func ReplayAndWaitForBuildToStart(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, api *utils.JenkinsAPI, path JobPath, job gojenkins.Job, buildNumber int, scripts *ReplayScripts, buildStartWaitTime time.Duration) (*gojenkins.Build, error) {
+	trigger := func() error {
+		return ReplayBuild(c, api, path, buildNumber, scripts)
+	}
+	return triggerWithAndWaitForBuildToStart(c, jenkins, job, trigger, buildStartWaitTime)
+}
+
+// ReplayAndWaitForBuildToFinish replays the build with the scripts then waits for the new Build to finish
+// or returns an error
+func ReplayAndWaitForBuildToFinish(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, api *utils.JenkinsAPI, path JobPath, job gojenkins.Job, buildNumber int, scripts *ReplayScripts, buildStartWaitTime time.Duration, buildFinishWaitTime time.Duration) (*gojenkins.Build, error) {
+	build, err := ReplayAndWaitForBuildToStart(c, jenkins, api, path, job, buildNumber, scripts, buildStartWaitTime)
+	if err != nil {
+		return build, err
+	}
+	if !build.Building {
+		return build, nil
+	}
+	return WaitForBuildToFinish(c, jenkins, job, build.Number, buildFinishWaitTime)
+}
@@ -0,0 +1,117 @@
+package jenkins
+
+import (
+	"fmt"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/DATA-DOG/godog/gherkin"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+type replayFeature struct {
+	Context *utils.ScenarioContext
+}
+
+// replayLastBuild replays the last completed build of the job after changing its scripts
+func (f *replayFeature) replayLastBuild(jobExpression string, change func(scripts *ReplayScripts) error) error {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
+	if err != nil {
+		return fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	job, err := GetJobByPath(jenkins, jobPath)
+	if err != nil {
+		return fmt.Errorf("error finding job %s due to %v", jobPath.FullName(), err)
+	}
+	last, err := jenkins.GetLastBuild(job)
+	if err != nil {
+		return fmt.Errorf("error finding the last build of job %s to replay due to %v", jobPath.FullName(), err)
+	}
+	if last.Building {
+		return fmt.Errorf("the last build #%d of job %s has not completed yet", last.Number, jobPath.FullName())
+	}
+	current := getCurrentBuild(f.Context)
+	err = current.Set(job, last.Number)
+	if err != nil {
+		return err
+	}
+	return f.replayBuild(current, change)
+}
+
+// replayBuild replays the current build after changing its scripts making the replayed build current
+func (f *replayFeature) replayBuild(current *currentBuild, change func(scripts *ReplayScripts) error) error {
+	if current.Number == 0 {
+		return fmt.Errorf("No build has been triggered, replayed or restarted yet")
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
+	if err != nil {
+		return fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	scripts, err := GetReplayScripts(api, current.Path, current.Number)
+	if err != nil {
+		return err
+	}
+	err = change(scripts)
+	if err != nil {
+		return err
+	}
+	build, err := ReplayAndWaitForBuildToStart(f.Context, jenkins, api, current.Path, current.Job, current.Number, scripts, f.Context.Timeout(utils.TimeoutBuildStart))
+	if err != nil {
+		return err
+	}
+	return current.Set(current.Job, build.Number)
+}
+
+func replaceMainScript(script string) func(scripts *ReplayScripts) error {
+	return func(scripts *ReplayScripts) error {
+		scripts.MainScript = script
+		return nil
+	}
+}
+
+func (f *replayFeature) iReplayTheLastBuildOfWithTheScript(jobExpression string, content *gherkin.DocString) error {
+	return f.replayLastBuild(jobExpression, replaceMainScript(content.Content))
+}
+
+func (f *replayFeature) iReplayTheLastBuildOfWithTheScriptFile(jobExpression string, fileName string) error {
+	script, err := utils.GetFileAsString(fileName)
+	if err != nil {
+		return err
+	}
+	return f.replayLastBuild(jobExpression, replaceMainScript(script))
+}
+
+func (f *replayFeature) iReplayTheLastBuildOfReplacingTheLoadedScriptWith(jobExpression string, name string, content *gherkin.DocString) error {
+	return f.replayLastBuild(jobExpression, func(scripts *ReplayScripts) error {
+		return scripts.SetLoadedScript(name, content.Content)
+	})
+}
+
+// iReplayTheBuildWithTheScript replays the build which the scenario last triggered, replayed or restarted
+func (f *replayFeature) iReplayTheBuildWithTheScript(content *gherkin.DocString) error {
+	current := getCurrentBuild(f.Context)
+	_, err := current.WaitForFinish()
+	if err != nil {
+		return err
+	}
+	return f.replayBuild(current, replaceMainScript(content.Content))
+}
+
+func ReplayFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	f := &replayFeature{
+		Context: c,
+	}
+
+	c.Step(`^I replay the last build of "([^"]*)" with the script:$`, f.iReplayTheLastBuildOfWithTheScript)
+	c.Step(`^I replay the last build of "([^"]*)" with the script "([^"]*)"$`, f.iReplayTheLastBuildOfWithTheScriptFile)
+	c.Step(`^I replay the last build of "([^"]*)" replacing the loaded script "([^"]*)" with:$`, f.iReplayTheLastBuildOfReplacingTheLoadedScriptWith)
+	c.Step(`^I replay the build with the script:$`, f.iReplayTheBuildWithTheScript)
+}
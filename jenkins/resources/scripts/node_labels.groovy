@@ -0,0 +1,5 @@
+// prints the labels of each node of Jenkins one per line as `<node>: <labels>`
+println "master: " + Jenkins.instance.labelString
+Jenkins.instance.nodes.each { node ->
+  println node.nodeName + ": " + node.labelString
+}
@@ -0,0 +1,2 @@
+// prints the number of items waiting in the build queue
+println Jenkins.instance.queue.items.length
@@ -0,0 +1,2 @@
+// prints true if Jenkins is preparing to shut down and is not starting any new builds
+println Jenkins.instance.isQuietingDown()
@@ -0,0 +1,117 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// StageResult is the status of a stage of a Pipeline build from the pipeline stage view API
+type StageResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Duration int64  `json:"durationMillis"`
+}
+
+func (s *StageResult) String() string {
+	return fmt.Sprintf("%s %s in %s", s.Name, s.Status, time.Duration(s.Duration)*time.Millisecond)
+}
+
+// buildPath returns the path of the build relative to the Jenkins URL
+func buildPath(path JobPath, buildNumber int) string {
+	return path.URLPath() + "/" + strconv.Itoa(buildNumber)
+}
+
+// GetRestartableStages returns the stages of a completed declarative Pipeline build which it can be restarted from
+func GetRestartableStages(api *utils.JenkinsAPI, path JobPath, buildNumber int) ([]string, error) {
+	restart := struct {
+		RestartEnabled    bool     `json:"restartEnabled"`
+		RestartableStages []string `json:"restartableStages"`
+	}{}
+	err := api.GetJSON(buildPath(path, buildNumber)+"/restart/api/json", &restart)
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return nil, fmt.Errorf("error job %s build #%d cannot be restarted from a stage as it is not a declarative pipeline", path.FullName(), buildNumber)
+		}
+		return nil, fmt.Errorf("error getting the restartable stages of job %s build #%d due to %v", path.FullName(), buildNumber, err)
+	}
+	if !restart.RestartEnabled {
+		return nil, fmt.Errorf("error job %s build #%d cannot be restarted from a stage yet", path.FullName(), buildNumber)
+	}
+	return restart.RestartableStages, nil
+}
+
+// RestartFromStage starts a new build of the job which restarts the declarative Pipeline build from the stage
+func RestartFromStage(c *utils.ScenarioContext, api *utils.JenkinsAPI, path JobPath, buildNumber int, stage string) error {
+	stages, err := GetRestartableStages(api, path, buildNumber)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, s := range stages {
+		if s == stage {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("error job %s build #%d cannot be restarted from stage %s. It can be restarted from: %s", path.FullName(), buildNumber, stage, strings.Join(stages, ", "))
+	}
+	form, err := json.Marshal(map[string]string{"stageName": stage})
+	if err != nil {
+		return fmt.Errorf("error marshalling the restart form due to %v", err)
+	}
+	values := url.Values{
+		"json":      {string(form)},
+		"stageName": {stage},
+	}
+	_, err = api.PostForm(buildPath(path, buildNumber)+"/restart/restart", values)
+	if err != nil {
+		return fmt.Errorf("error restarting job %s build #%d from stage %s due to %v", path.FullName(), buildNumber, stage, err)
+	}
+	c.LogInfof("restarting job %s build #%d from stage %s\n", path.FullName(), buildNumber, stage)
+	return nil
+}
+
+// RestartFromStageAndWaitForBuildToStart restarts the build from the stage and waits for the new Build to start
+// or returns an error
+func RestartFromStageAndWaitForBuildToStart(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, api *utils.JenkinsAPI, path JobPath, job gojenkins.Job, buildNumber int, stage string, buildStartWaitTime time.Duration) (*gojenkins.Build, error) {
+	trigger := func() error {
+		return RestartFromStage(c, api, path, buildNumber, stage)
+	}
+	return triggerWithAndWaitForBuildToStart(c, jenkins, job, trigger, buildStartWaitTime)
+}
+
+// GetStageResults returns the results of the stages of the Pipeline build
+func GetStageResults(api *utils.JenkinsAPI, path JobPath, buildNumber int) ([]*StageResult, error) {
+	describe := struct {
+		Stages []*StageResult `json:"stages"`
+	}{}
+	err := api.GetJSON(buildPath(path, buildNumber)+"/wfapi/describe", &describe)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the stages of job %s build #%d due to %v", path.FullName(), buildNumber, err)
+	}
+	return describe.Stages, nil
+}
+
+// WaitForBuildToFinishWithStages waits for the build to finish then returns it along with the results of its stages
+func WaitForBuildToFinishWithStages(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, api *utils.JenkinsAPI, path JobPath, job gojenkins.Job, buildNumber int, buildFinishWaitTime time.Duration) (*gojenkins.Build, []*StageResult, error) {
+	build, err := WaitForBuildToFinish(c, jenkins, job, buildNumber, buildFinishWaitTime)
+	if err != nil {
+		return build, nil, err
+	}
+	stages, err := GetStageResults(api, path, buildNumber)
+	if err != nil {
+		return build, nil, err
+	}
+	for _, stage := range stages {
+		c.LogInfof("job %s build #%d stage %s\n", job.Url, buildNumber, stage)
+	}
+	return build, stages, nil
+}
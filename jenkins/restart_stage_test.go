@@ -0,0 +1,105 @@
+package jenkins
+
+import (
+	"fmt"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+type restartStageFeature struct {
+	Context *utils.ScenarioContext
+}
+
+// findJob makes the job current so that its builds can be restarted
+func (f *restartStageFeature) findJob(jobExpression string) (*currentBuild, error) {
+	jobPath, err := ParseJobPath(f.Context, jobExpression, nil)
+	if err != nil {
+		return nil, err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
+	if err != nil {
+		return nil, fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	job, err := GetJobByPath(jenkins, jobPath)
+	if err != nil {
+		return nil, fmt.Errorf("error finding job %s due to %v", jobPath.FullName(), err)
+	}
+	current := getCurrentBuild(f.Context)
+	return current, current.SetJob(job)
+}
+
+// restart restarts the build of the current job from the stage making the restarted build current
+func (f *restartStageFeature) restart(current *currentBuild, buildNumber int, stage string) error {
+	jenkins, err := utils.GetJenkinsClient(f.Context)
+	if err != nil {
+		return fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	api, err := getJenkinsAPI(f.Context)
+	if err != nil {
+		return err
+	}
+	build, err := RestartFromStageAndWaitForBuildToStart(f.Context, jenkins, api, current.Path, current.Job, buildNumber, stage, f.Context.Timeout(utils.TimeoutBuildStart))
+	if err != nil {
+		return err
+	}
+	return current.Set(current.Job, build.Number)
+}
+
+func (f *restartStageFeature) iRestartBuildOfFromStage(buildNumber int, jobExpression string, stage string) error {
+	current, err := f.findJob(jobExpression)
+	if err != nil {
+		return err
+	}
+	return f.restart(current, buildNumber, stage)
+}
+
+func (f *restartStageFeature) iRestartTheLastBuildOfFromStage(jobExpression string, stage string) error {
+	current, err := f.findJob(jobExpression)
+	if err != nil {
+		return err
+	}
+	jenkins, err := utils.GetJenkinsClient(f.Context)
+	if err != nil {
+		return fmt.Errorf("error getting a Jenkins client %v", err)
+	}
+	last, err := jenkins.GetLastBuild(current.Job)
+	if err != nil {
+		return fmt.Errorf("error finding the last build of job %s to restart due to %v", current.Path.FullName(), err)
+	}
+	return f.restart(current, last.Number, stage)
+}
+
+// iRestartBuildFromStage restarts a build of the job which the scenario last triggered, replayed or restarted
+// a build of, such as the restarted build itself
+func (f *restartStageFeature) iRestartBuildFromStage(buildNumber int, stage string) error {
+	current := getCurrentBuild(f.Context)
+	err := current.assertJob()
+	if err != nil {
+		return err
+	}
+	return f.restart(current, buildNumber, stage)
+}
+
+// iRestartTheBuildFromStage restarts the build which the scenario last triggered, replayed or restarted
+// once it has finished
+func (f *restartStageFeature) iRestartTheBuildFromStage(stage string) error {
+	current := getCurrentBuild(f.Context)
+	_, err := current.WaitForFinish()
+	if err != nil {
+		return err
+	}
+	return f.restart(current, current.Number, stage)
+}
+
+func RestartStageFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	f := &restartStageFeature{
+		Context: c,
+	}
+
+	c.Step(`^I restart build (\d+) of "([^"]*)" from stage "([^"]*)"$`, f.iRestartBuildOfFromStage)
+	c.Step(`^I restart the last build of "([^"]*)" from stage "([^"]*)"$`, f.iRestartTheLastBuildOfFromStage)
+	c.Step(`^I restart build (\d+) from stage "([^"]*)"$`, f.iRestartBuildFromStage)
+	c.Step(`^I restart the build from stage "([^"]*)"$`, f.iRestartTheBuildFromStage)
+}
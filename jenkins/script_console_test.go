@@ -0,0 +1,118 @@
+package jenkins
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/DATA-DOG/godog/gherkin"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+type scriptConsoleFeature struct {
+	Context *utils.ScenarioContext
+	API     *utils.JenkinsAPI
+	Output  string
+	Ran     bool
+}
+
+func (f *scriptConsoleFeature) runScript(name string, script string) error {
+	if f.API == nil {
+		api, err := getJenkinsAPI(f.Context)
+		if err != nil {
+			return err
+		}
+		f.API = api
+	}
+	f.Context.LogInfof("running the Jenkins script %s\n", name)
+	output, err := f.API.RunScript(script)
+	f.Output = output
+	f.Ran = true
+	return err
+}
+
+func (f *scriptConsoleFeature) iRunTheJenkinsScript(content *gherkin.DocString) error {
+	err := utils.CheckInlineScriptAllowed()
+	if err != nil {
+		return err
+	}
+	return f.runScript("inline", content.Content)
+}
+
+func (f *scriptConsoleFeature) iRunTheJenkinsScriptFile(name string) error {
+	script, err := utils.LoadScript(name)
+	if err != nil {
+		return err
+	}
+	return f.runScript(name, script)
+}
+
+func (f *scriptConsoleFeature) output() (string, error) {
+	if !f.Ran {
+		return "", fmt.Errorf("No Jenkins script has been run yet")
+	}
+	return strings.TrimSpace(f.Output), nil
+}
+
+func (f *scriptConsoleFeature) theScriptOutputShouldBe(expected string) error {
+	actual, err := f.output()
+	if err != nil {
+		return err
+	}
+	expected = strings.TrimSpace(f.Context.ReplaceVariables(expected, nil))
+	if actual != expected {
+		return fmt.Errorf("the script output is %q but expected %q", actual, expected)
+	}
+	return nil
+}
+
+func (f *scriptConsoleFeature) theScriptOutputShouldBeDocString(content *gherkin.DocString) error {
+	return f.theScriptOutputShouldBe(content.Content)
+}
+
+func (f *scriptConsoleFeature) theScriptOutputShouldContain(expected string) error {
+	actual, err := f.output()
+	if err != nil {
+		return err
+	}
+	expected = f.Context.ReplaceVariables(expected, nil)
+	if !strings.Contains(actual, expected) {
+		return fmt.Errorf("the script output does not contain %q: %s", expected, actual)
+	}
+	return nil
+}
+
+func (f *scriptConsoleFeature) theScriptOutputShouldMatch(pattern string) error {
+	actual, err := f.output()
+	if err != nil {
+		return err
+	}
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regular expression %s due to %v", pattern, err)
+	}
+	if !r.MatchString(actual) {
+		return fmt.Errorf("the script output does not match %s: %s", pattern, actual)
+	}
+	return nil
+}
+
+func ScriptConsoleFeatureContext(s *godog.Suite) {
+	c := utils.SuiteContext(s)
+	f := &scriptConsoleFeature{
+		Context: c,
+	}
+
+	s.BeforeScenario(func(interface{}) {
+		f.Output = ""
+		f.Ran = false
+	})
+
+	c.Step(`^I run the Jenkins script:$`, f.iRunTheJenkinsScript)
+	c.Step(`^I run the Jenkins script "([^"]*)"$`, f.iRunTheJenkinsScriptFile)
+	c.Step(`^the script output should be "([^"]*)"$`, f.theScriptOutputShouldBe)
+	c.Step(`^the script output should be:$`, f.theScriptOutputShouldBeDocString)
+	c.Step(`^the script output should contain "([^"]*)"$`, f.theScriptOutputShouldContain)
+	c.Step(`^the script output should match "([^"]*)"$`, f.theScriptOutputShouldMatch)
+}
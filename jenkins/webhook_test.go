@@ -0,0 +1,102 @@
+package jenkins
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/github"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+)
+
+func (p *pullRequestFeature) gitHubSendsAPushWebhookForBranchOfTheFork(branch string) error {
+	if p.Forker == nil {
+		return fmt.Errorf("No fork has been created yet")
+	}
+	branch = p.Context.ReplaceVariables(branch, p.Forker.Variables())
+	gitcmder := p.Forker.GitCommander
+	after, err := p.branchSha(branch)
+	if err != nil {
+		return err
+	}
+	before := p.WebhookShas[branch]
+	if before == "" && branch == p.Forker.ResetBranch() {
+		// the branch was reset from the upstream repository when the fork was created
+		before, err = gitcmder.GetLastCommitSha(p.Forker.UpstreamDir)
+		if err != nil {
+			return err
+		}
+	}
+	if before == after {
+		before = ""
+	}
+	forkRepo, err := github.ParseUserRepositoryName(p.Forker.ForkedRepoName)
+	if err != nil {
+		return err
+	}
+	repo, err := github.GetRepository(p.GitHubClient, forkRepo.Organisation, forkRepo.Repository)
+	if err != nil {
+		return err
+	}
+	webhook, err := github.PushWebhook(repo, branch, before, after)
+	if err != nil {
+		return err
+	}
+	err = p.sendWebhook(webhook)
+	if err != nil {
+		return err
+	}
+	p.WebhookShas[branch] = after
+	return nil
+}
+
+// branchSha returns the last commit of the branch of the fork which may not be the branch checked out
+func (p *pullRequestFeature) branchSha(branch string) (string, error) {
+	if p.Forker.PushedBranch == branch && p.Forker.PushedSha != "" {
+		return p.Forker.PushedSha, nil
+	}
+	backend, err := p.Forker.GitCommander.GetBackend()
+	if err != nil {
+		return "", err
+	}
+	return backend.RevParse(p.Forker.ForkDir, branch)
+}
+
+func (p *pullRequestFeature) gitHubSendsAPullRequestWebhookWithAction(action string) error {
+	if p.PullRequest == nil {
+		return fmt.Errorf("No pull request has been opened yet")
+	}
+	webhook, err := github.PullRequestWebhook(p.PullRequest, action, p.headSha())
+	if err != nil {
+		return err
+	}
+	return p.sendWebhook(webhook)
+}
+
+func (p *pullRequestFeature) sendWebhook(webhook *github.Webhook) error {
+	api, err := getJenkinsAPI(p.Context)
+	if err != nil {
+		return err
+	}
+	p.WebhookTime, err = SendGitHubWebhook(p.Context, api, webhook, github.WebhookSecret())
+	return err
+}
+
+func (p *pullRequestFeature) theJobShouldStartABuildWithin(jobExpression string, amount int, unit string) error {
+	if p.WebhookTime.IsZero() {
+		return fmt.Errorf("No webhook has been sent yet")
+	}
+	timeout, err := utils.ParseDuration(amount, unit)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	job, err := waitForJobByExpression(p.Context, p.Jenkins, jobExpression, p.Forker.Variables(), timeout)
+	if err != nil {
+		return err
+	}
+	build, err := WaitForBuildStartedSince(p.Context, p.Jenkins, job, p.WebhookTime, timeout-time.Since(start))
+	if err != nil {
+		return err
+	}
+	return getCurrentBuild(p.Context).Set(job, build.Number)
+}
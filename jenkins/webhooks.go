@@ -0,0 +1,73 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fabric8-jenkins/godog-jenkins/github"
+	"github.com/fabric8-jenkins/godog-jenkins/utils"
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// the path of the endpoint of the Jenkins GitHub plugin which receives webhooks
+const gitHubWebhookPath = "github-webhook/"
+
+// SendGitHubWebhook posts the webhook to Jenkins as GitHub would signing it with the secret if it is not empty.
+// It returns the time Jenkins received the webhook according to the clock of Jenkins
+func SendGitHubWebhook(c *utils.ScenarioContext, api *utils.JenkinsAPI, webhook *github.Webhook, secret string) (time.Time, error) {
+	sent := time.Now()
+	header, _, err := api.DoWithHeaders("POST", gitHubWebhookPath, webhook.Headers(secret), webhook.Payload)
+	event := &utils.Event{
+		Type:    utils.EventWebhookSent,
+		Method:  "POST",
+		Path:    gitHubWebhookPath,
+		Webhook: webhook.Event,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	c.RecordEvent(event)
+	if err != nil {
+		return sent, fmt.Errorf("error sending the %s to Jenkins due to %v", webhook, err)
+	}
+	c.LogInfof("sent the %s to Jenkins\n", webhook)
+
+	// lets use the clock of Jenkins to find builds started by the webhook
+	received, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		c.LogInfof("WARNING: no Date header in the Jenkins response so using the local time\n")
+		return sent, nil
+	}
+	return received, nil
+}
+
+// WaitForBuildStartedSince waits for the job to have a build started at or after the given time of the Jenkins clock
+func WaitForBuildStartedSince(c *utils.ScenarioContext, jenkins *gojenkins.Jenkins, job gojenkins.Job, since time.Time, timeout time.Duration) (*gojenkins.Build, error) {
+	jobUrl := job.Url
+	// the Date header only has a precision of seconds
+	sinceMillis := since.Add(-1*time.Second).UnixNano() / int64(time.Millisecond)
+	var result *gojenkins.Build
+	fn := func() (bool, error) {
+		build, err := jenkins.GetLastBuild(job)
+		if err != nil {
+			if Is404(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("error finding last build for %s due to %v", jobUrl, err)
+		}
+		if int64(build.Timestamp) < sinceMillis {
+			return false, nil
+		}
+		c.LogInfof("job %s started build #%d\n", jobUrl, build.Number)
+		c.RecordEvent(&utils.Event{
+			Type:  utils.EventBuildStarted,
+			Job:   jobFullName(job),
+			Build: build.Number,
+		})
+		result = &build
+		return true, nil
+	}
+	err := c.PollPhase(utils.TimeoutBuildStart, 1*time.Second, timeout, fmt.Sprintf("a build to start for %s", jobUrl), fn)
+	return result, err
+}
@@ -0,0 +1,368 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+const (
+	// CassetteRecord records the HTTP traffic of each scenario to cassette files
+	CassetteRecord = "record"
+	// CassetteReplay serves the HTTP traffic of each scenario from the recorded cassette files
+	CassetteReplay = "replay"
+
+	defaultCassetteDir     = "cassettes"
+	defaultTimeCompression = 100
+
+	// scenarioIDPlaceholder replaces the scenario ID in cassettes so they can be replayed by another run
+	scenarioIDPlaceholder = "${SCENARIO_ID}"
+
+	// timestampPlaceholder replaces the timestamps in request bodies, such as the time of a push webhook,
+	// so that the requests of a replay match the recorded ones
+	timestampPlaceholder = "${TIMESTAMP}"
+)
+
+// response headers which are not written to cassettes. The Date header is kept as steps use the clock
+// of Jenkins to find the builds they started
+var ignoredCassetteHeaders = map[string]bool{
+	"Set-Cookie": true,
+}
+
+var requestTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// Interaction is a request and its response which is written as a line of JSON to a cassette
+type Interaction struct {
+	Method string `json:"method"`
+	// URL is the path and query of the request so cassettes can be replayed against any server
+	URL         string      `json:"url"`
+	RequestBody string      `json:"requestBody,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body,omitempty"`
+}
+
+type cassette struct {
+	fileName string
+	lock     sync.Mutex
+	file     *os.File
+	// the recorded interactions of each request with the number of them served so far
+	interactions map[string][]*Interaction
+	served       map[string]int
+}
+
+var (
+	cassetteLock sync.Mutex
+	cassettes    = map[string]*cassette{}
+)
+
+// CassetteMode returns the cassette mode from $BDD_CASSETTE_MODE which is either record, replay or empty if disabled
+func CassetteMode() string {
+	mode := os.Getenv("BDD_CASSETTE_MODE")
+	switch mode {
+	case CassetteRecord, CassetteReplay:
+		return mode
+	}
+	return ""
+}
+
+// CassetteDir returns the directory of the cassette files from $BDD_CASSETTE_DIR which defaults to `cassettes`
+func CassetteDir() string {
+	dir := os.Getenv("BDD_CASSETTE_DIR")
+	if dir == "" {
+		dir = defaultCassetteDir
+	}
+	return dir
+}
+
+// TimeCompression returns the factor that sleeps and polling loops are shortened by when replaying cassettes
+// from $BDD_CASSETTE_TIME_COMPRESSION which defaults to 100. It is 1 when not replaying
+func TimeCompression() int64 {
+	if CassetteMode() != CassetteReplay {
+		return 1
+	}
+	factor, err := strconv.ParseInt(os.Getenv("BDD_CASSETTE_TIME_COMPRESSION"), 10, 64)
+	if err != nil || factor < 1 {
+		return defaultTimeCompression
+	}
+	return factor
+}
+
+// Sleep sleeps for the duration shortened by the TimeCompression
+func Sleep(d time.Duration) {
+	time.Sleep(compressDuration(d))
+}
+
+// Poll polls the function until it returns true, an error or the timeout with the period and timeout
+// shortened by the TimeCompression
+func Poll(pollPeriod time.Duration, timeout time.Duration, timeoutFailureMessage string, fn gojenkins.ConditionFunc) error {
+	return gojenkins.Poll(compressDuration(pollPeriod), compressDuration(timeout), timeoutFailureMessage, fn)
+}
+
+func compressDuration(d time.Duration) time.Duration {
+	return d / time.Duration(TimeCompression())
+}
+
+// CassetteTransport is a http.RoundTripper which records the requests and responses of the running scenario
+// of the Context to the cassette <name> in $BDD_CASSETTE_DIR or replays them from it depending on the CassetteMode
+type CassetteTransport struct {
+	Name      string
+	Context   *ScenarioContext
+	Transport http.RoundTripper
+}
+
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	mode := CassetteMode()
+	if mode == "" {
+		return transport.RoundTrip(req)
+	}
+	c, err := getCassette(t.cassetteFileName(), mode)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	interaction := &Interaction{
+		Method:      req.Method,
+		URL:         t.normalizeCassetteText(req.URL.RequestURI()),
+		RequestBody: requestTimestamp.ReplaceAllString(t.normalizeCassetteText(requestBody), timestampPlaceholder),
+	}
+	if mode == CassetteReplay {
+		return c.replay(req, interaction, t.restoreCassetteText)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the response of %s %s due to %v", req.Method, Redact(req.URL.String()), err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	interaction.Status = resp.StatusCode
+	interaction.Header = http.Header{}
+	for name, values := range resp.Header {
+		if ignoredCassetteHeaders[name] {
+			continue
+		}
+		for _, value := range values {
+			interaction.Header.Add(name, t.normalizeCassetteText(value))
+		}
+	}
+	interaction.Body = t.normalizeCassetteText(string(data))
+	return resp, c.record(interaction)
+}
+
+// cassetteFileName returns the cassette of the running scenario or of the cassette name if there is no scenario
+func (t *CassetteTransport) cassetteFileName() string {
+	name := SanitizeFileName(t.Name)
+	if scenario := t.Context.Scenario(); scenario != nil && scenario.ReportName != "" {
+		return filepath.Join(CassetteDir(), scenario.ReportName+"-"+name+".jsonl")
+	}
+	return filepath.Join(CassetteDir(), name+".jsonl")
+}
+
+// getCassette returns the cassette for the file, truncating it when recording or loading it when replaying
+func getCassette(fileName string, mode string) (*cassette, error) {
+	cassetteLock.Lock()
+	defer cassetteLock.Unlock()
+
+	c := cassettes[fileName]
+	if c != nil {
+		return c, nil
+	}
+	c = &cassette{
+		fileName: fileName,
+	}
+	var err error
+	if mode == CassetteRecord {
+		err = c.create()
+	} else {
+		err = c.load()
+	}
+	if err != nil {
+		return nil, err
+	}
+	cassettes[fileName] = c
+	return c, nil
+}
+
+// closeCassettes closes the cassettes of the scenario with the report name
+func closeCassettes(reportName string) {
+	if reportName == "" {
+		return
+	}
+	prefix := filepath.Join(CassetteDir(), reportName) + "-"
+
+	cassetteLock.Lock()
+	defer cassetteLock.Unlock()
+	for fileName, c := range cassettes {
+		if !strings.HasPrefix(fileName, prefix) {
+			continue
+		}
+		c.lock.Lock()
+		if c.file != nil {
+			c.file.Close()
+			c.file = nil
+		}
+		c.lock.Unlock()
+		delete(cassettes, fileName)
+	}
+}
+
+func (c *cassette) create() error {
+	dir := filepath.Dir(c.fileName)
+	err := os.MkdirAll(dir, 0770)
+	if err != nil {
+		return fmt.Errorf("Failed to create the cassette directory %s due to %v", dir, err)
+	}
+	c.file, err = os.Create(c.fileName)
+	if err != nil {
+		return fmt.Errorf("Failed to create the cassette %s due to %v", c.fileName, err)
+	}
+	return nil
+}
+
+func (c *cassette) load() error {
+	file, err := os.Open(c.fileName)
+	if err != nil {
+		return fmt.Errorf("Failed to open the cassette %s due to %v", c.fileName, err)
+	}
+	defer file.Close()
+
+	c.interactions = map[string][]*Interaction{}
+	c.served = map[string]int{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		interaction := &Interaction{}
+		err = json.Unmarshal(scanner.Bytes(), interaction)
+		if err != nil {
+			return fmt.Errorf("Failed to parse line %d of the cassette %s due to %v", line, c.fileName, err)
+		}
+		key := interaction.key()
+		c.interactions[key] = append(c.interactions[key], interaction)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("Failed to read the cassette %s due to %v", c.fileName, err)
+	}
+	return nil
+}
+
+func (c *cassette) record(interaction *Interaction) error {
+	data, err := json.Marshal(interaction)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal the %s %s interaction due to %v", interaction.Method, interaction.URL, err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	_, err = c.file.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("Failed to write to the cassette %s due to %v", c.fileName, err)
+	}
+	return nil
+}
+
+// replay returns the next recorded response to the request, repeating the last one once they have all
+// been served so that polling loops see the final state. The restore function puts back the scenario ID
+func (c *cassette) replay(req *http.Request, interaction *Interaction, restore func(string) string) (*http.Response, error) {
+	key := interaction.key()
+
+	c.lock.Lock()
+	recorded := c.interactions[key]
+	if len(recorded) == 0 {
+		c.lock.Unlock()
+		return nil, fmt.Errorf("No recorded response for %s %s in the cassette %s", interaction.Method, interaction.URL, c.fileName)
+	}
+	i := c.served[key]
+	if i < len(recorded)-1 {
+		c.served[key] = i + 1
+	} else {
+		i = len(recorded) - 1
+	}
+	c.lock.Unlock()
+
+	found := recorded[i]
+	header := http.Header{}
+	for name, values := range found.Header {
+		for _, value := range values {
+			header.Add(name, restore(value))
+		}
+	}
+	body := restore(found.Body)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return &http.Response{
+		Status:        strconv.Itoa(found.Status) + " " + http.StatusText(found.Status),
+		StatusCode:    found.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// key returns the key used to match a request with the recorded interactions
+func (i *Interaction) key() string {
+	return i.Method + " " + i.URL + "\n" + i.RequestBody
+}
+
+// readRequestBody returns the body of the request, replacing it so that it can be sent
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("Failed to read the body of %s %s due to %v", req.Method, Redact(req.URL.String()), err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+// normalizeCassetteText redacts the secrets and replaces the running scenario ID with a placeholder
+// so that the text is the same in every run
+func (t *CassetteTransport) normalizeCassetteText(text string) string {
+	text = Redact(text)
+	if scenario := t.Context.Scenario(); scenario != nil && scenario.ID != "" {
+		text = strings.Replace(text, scenario.ID, scenarioIDPlaceholder, -1)
+	}
+	return text
+}
+
+// restoreCassetteText replaces the scenario ID placeholder with the ID of the running scenario
+func (t *CassetteTransport) restoreCassetteText(text string) string {
+	if scenario := t.Context.Scenario(); scenario != nil && scenario.ID != "" {
+		text = strings.Replace(text, scenarioIDPlaceholder, scenario.ID, -1)
+	}
+	return text
+}
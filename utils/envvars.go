@@ -29,3 +29,21 @@ func ReplaceEnvVars(expression string) string {
 	}
 	return expression
 }
+
+// ReplaceVariables replaces all of the given variable expressions, the variables of the running scenario and then
+// any environment variable expressions in the given string
+func (c *ScenarioContext) ReplaceVariables(expression string, variables map[string]string) string {
+	expression = replaceVariables(expression, variables)
+	if scenario := c.Scenario(); scenario != nil {
+		expression = replaceVariables(expression, scenario.Variables())
+	}
+	return ReplaceEnvVars(expression)
+}
+
+func replaceVariables(expression string, variables map[string]string) string {
+	for name, value := range variables {
+		expression = strings.Replace(expression, "${"+name+"}", value, -1)
+		expression = strings.Replace(expression, "$"+name, value, -1)
+	}
+	return expression
+}
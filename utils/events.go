@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DATA-DOG/godog/gherkin"
+)
+
+// the types of the events recorded in the event log
+const (
+	EventScenarioStarted  = "scenario.started"
+	EventScenarioFinished = "scenario.finished"
+	EventStepStarted      = "step.started"
+	EventStepFinished     = "step.finished"
+	EventJenkinsRequest   = "jenkins.request"
+	EventGitHubRequest    = "github.request"
+	EventGitCommand       = "git.command"
+	EventJobCreated       = "job.created"
+	EventJobDeleted       = "job.deleted"
+	EventBuildTriggered   = "build.triggered"
+	EventBuildStarted     = "build.started"
+	EventBuildFinished    = "build.finished"
+	EventWebhookSent      = "webhook.sent"
+)
+
+// Event is an action of the test harness which is written as a line of JSON to the event log
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Feature  string    `json:"feature,omitempty"`
+	Scenario string    `json:"scenario,omitempty"`
+	// ScenarioName is the name of the scenario in the feature file
+	ScenarioName string `json:"scenarioName,omitempty"`
+	Step         string `json:"step,omitempty"`
+	// StepLine is the line of the step in the feature file
+	StepLine int `json:"stepLine,omitempty"`
+
+	Method   string `json:"method,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Job      string `json:"job,omitempty"`
+	Build    int    `json:"build,omitempty"`
+	Result   string `json:"result,omitempty"`
+	Command  string `json:"command,omitempty"`
+	Duration int64  `json:"durationMs,omitempty"`
+	// RateLimitRemaining is the number of GitHub API requests left before the rate limit resets
+	RateLimitRemaining *int   `json:"rateLimitRemaining,omitempty"`
+	Error              string `json:"error,omitempty"`
+	// Webhook is the GitHub event of a webhook sent to Jenkins
+	Webhook string `json:"webhook,omitempty"`
+}
+
+var (
+	eventLock sync.Mutex
+	eventFile *os.File
+	eventOnce sync.Once
+)
+
+// EventsEnabled returns true if the event log is enabled via $BDD_EVENTS_FILE
+func EventsEnabled() bool {
+	return os.Getenv("BDD_EVENTS_FILE") != ""
+}
+
+// RecordEvent writes the event to the event log in $BDD_EVENTS_FILE adding the time and the running
+// scenario and step. It does nothing if the event log is not enabled
+func (c *ScenarioContext) RecordEvent(event *Event) {
+	if !EventsEnabled() {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if scenario := c.Scenario(); scenario != nil {
+		event.Feature = scenario.Feature
+		event.Scenario = scenario.ID
+		event.ScenarioName = scenario.Name
+		if event.Step == "" {
+			event.Step, event.StepLine = scenario.currentStep()
+		}
+	}
+	event.Path = Redact(event.Path)
+	event.Command = Redact(event.Command)
+	event.Error = Redact(event.Error)
+	data, err := json.Marshal(event)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to marshal event %s due to %v\n", event.Type, err)
+		return
+	}
+
+	eventOnce.Do(openEventFile)
+
+	eventLock.Lock()
+	defer eventLock.Unlock()
+	if eventFile == nil {
+		return
+	}
+	_, err = eventFile.Write(append(data, '\n'))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to write event %s due to %v\n", event.Type, err)
+	}
+}
+
+// RecordDuration records the event with the time since the start and the error if there was one
+func (c *ScenarioContext) RecordDuration(event *Event, start time.Time, err error) {
+	event.Duration = int64(time.Since(start) / time.Millisecond)
+	if err != nil {
+		event.Error = err.Error()
+	}
+	c.RecordEvent(event)
+}
+
+// startStep records the step as the current step of the scenario
+func (c *ScenarioContext) startStep(step *gherkin.Step) {
+	if scenario := c.Scenario(); scenario != nil {
+		scenario.setCurrentStep(step)
+	}
+	c.RecordEvent(&Event{
+		Type:     EventStepStarted,
+		Step:     step.Text,
+		StepLine: stepLine(step),
+	})
+}
+
+// endStep records the end of the step and clears the current step of the scenario
+func (c *ScenarioContext) endStep(step *gherkin.Step, err error) {
+	event := &Event{
+		Type:     EventStepFinished,
+		Step:     step.Text,
+		StepLine: stepLine(step),
+		Result:   "passed",
+	}
+	if err != nil {
+		event.Result = "failed"
+		event.Error = err.Error()
+	}
+	c.RecordEvent(event)
+	if scenario := c.Scenario(); scenario != nil {
+		scenario.setCurrentStep(nil)
+	}
+}
+
+// EventTransport is a http.RoundTripper which records an event of the given type for each request
+// against the running scenario of the Context
+type EventTransport struct {
+	Type      string
+	Context   *ScenarioContext
+	Transport http.RoundTripper
+}
+
+func (t *EventTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	if EventsEnabled() {
+		event := &Event{
+			Type:   t.Type,
+			Method: req.Method,
+			Path:   req.URL.Path,
+		}
+		if resp != nil {
+			event.Status = resp.StatusCode
+		}
+		t.Context.RecordDuration(event, start, err)
+	}
+	return resp, err
+}
+
+// CommandLine returns the command line of the program and arguments
+func CommandLine(prog string, args ...string) string {
+	return strings.TrimSpace(prog + " " + strings.Join(args, " "))
+}
+
+func openEventFile() {
+	fileName := os.Getenv("BDD_EVENTS_FILE")
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0660)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: disabling the event log as failed to open %s due to %v\n", fileName, err)
+		return
+	}
+	eventFile = file
+}
+
+func stepLine(step *gherkin.Step) int {
+	if step.Location == nil {
+		return 0
+	}
+	return step.Location.Line
+}
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// JenkinsAPI performs raw requests against the parts of the Jenkins REST API which the vendored client
+// does not support using the credentials from the BDD_JENKINS_* env vars. POST requests include a CSRF
+// crumb when the crumb issuer is enabled
+type JenkinsAPI struct {
+	URL    string
+	Auth   *gojenkins.Auth
+	Client *http.Client
+
+	crumbLock  sync.Mutex
+	crumbField string
+	crumb      string
+	crumbValid bool
+}
+
+// JenkinsAPIError is returned when Jenkins responds with a status which is not successful
+type JenkinsAPIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *JenkinsAPIError) Error() string {
+	message := fmt.Sprintf("%s %s returned status %d", e.Method, e.Path, e.StatusCode)
+	body := strings.TrimSpace(e.Body)
+	if body != "" && len(body) < 500 {
+		message += ": " + body
+	}
+	return Redact(message)
+}
+
+// IsNotFound returns true if the error is a JenkinsAPIError for a 404
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*JenkinsAPIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// GetJenkinsAPI creates a JenkinsAPI from the BDD_JENKINS_* env vars which records its requests against
+// the running scenario of the context
+func GetJenkinsAPI(c *ScenarioContext) (*JenkinsAPI, error) {
+	url, auth, err := jenkinsURLAndAuth()
+	if err != nil {
+		return nil, err
+	}
+	client := newJenkinsHTTPClient(c)
+	// newer versions of Jenkins only accept a crumb from the same session
+	client.Jar, err = cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the cookie jar due to %v", err)
+	}
+	return &JenkinsAPI{
+		URL:    strings.TrimSuffix(url, "/"),
+		Auth:   auth,
+		Client: client,
+	}, nil
+}
+
+// Get returns the body of the path relative to the Jenkins URL
+func (a *JenkinsAPI) Get(path string) ([]byte, error) {
+	return a.Do("GET", path, "", nil)
+}
+
+// GetJSON parses the JSON of the path relative to the Jenkins URL into the result
+func (a *JenkinsAPI) GetJSON(path string, result interface{}) error {
+	data, err := a.Get(path)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return fmt.Errorf("Failed to parse the JSON of %s due to %v", path, err)
+	}
+	return nil
+}
+
+// Post posts the body with the content type to the path relative to the Jenkins URL returning the response body
+func (a *JenkinsAPI) Post(path string, contentType string, body []byte) ([]byte, error) {
+	return a.Do("POST", path, contentType, body)
+}
+
+// PostForm posts the form values to the path relative to the Jenkins URL returning the response body
+func (a *JenkinsAPI) PostForm(path string, values url.Values) ([]byte, error) {
+	return a.Do("POST", path, "application/x-www-form-urlencoded", []byte(values.Encode()))
+}
+
+// Do performs the request returning the response body or a JenkinsAPIError if the response is not successful.
+// Redirects are treated as success as Jenkins redirects after most form posts
+func (a *JenkinsAPI) Do(method string, path string, contentType string, body []byte) ([]byte, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	_, data, err := a.DoWithHeaders(method, path, header, body)
+	return data, err
+}
+
+// DoWithHeaders performs the request with the headers returning the response headers and body or a
+// JenkinsAPIError if the response is not successful
+func (a *JenkinsAPI) DoWithHeaders(method string, path string, header http.Header, body []byte) (http.Header, []byte, error) {
+	resp, err := a.send(method, path, header, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read the response of %s %s due to %v", method, path, err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, nil, &JenkinsAPIError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Body:       string(data),
+		}
+	}
+	return resp.Header, data, nil
+}
+
+func (a *JenkinsAPI) send(method string, path string, header http.Header, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, a.URL+"/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create request %s %s due to %v", method, path, err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	a.authenticate(req)
+	if method != "GET" {
+		field, crumb, err := a.getCrumb()
+		if err != nil {
+			return nil, err
+		}
+		if field != "" {
+			req.Header.Set(field, crumb)
+		}
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, RedactError(fmt.Errorf("Failed to %s %s due to %v", method, path, err))
+	}
+	return resp, nil
+}
+
+func (a *JenkinsAPI) authenticate(req *http.Request) {
+	if a.Auth == nil {
+		return
+	}
+	if a.Auth.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Auth.BearerToken)
+	} else {
+		req.SetBasicAuth(a.Auth.Username, a.Auth.ApiToken)
+	}
+}
+
+// getCrumb returns the CSRF crumb header and value or empty strings if the crumb issuer is disabled
+func (a *JenkinsAPI) getCrumb() (string, string, error) {
+	a.crumbLock.Lock()
+	defer a.crumbLock.Unlock()
+
+	if a.crumbValid {
+		return a.crumbField, a.crumb, nil
+	}
+	crumb := struct {
+		Crumb             string `json:"crumb"`
+		CrumbRequestField string `json:"crumbRequestField"`
+	}{}
+	err := a.GetJSON("crumbIssuer/api/json", &crumb)
+	if err != nil && !IsNotFound(err) {
+		return "", "", fmt.Errorf("Failed to get the CSRF crumb due to %v", err)
+	}
+	a.crumbField = crumb.CrumbRequestField
+	a.crumb = crumb.Crumb
+	a.crumbValid = true
+	return a.crumbField, a.crumb, nil
+}
@@ -3,28 +3,104 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
 const (
 	infoPrefix = "      "
+
+	colorReset = "\x1b[0m"
+
+	// ColorAuto uses colours when the output is a terminal
+	ColorAuto = "auto"
+	// ColorAlways always uses colours
+	ColorAlways = "always"
+	// ColorNever never uses colours
+	ColorNever = "never"
 )
 
-// LogInfo info logging
+// LogInfo info logging to the console for output which does not belong to a scenario
 func LogInfo(message string) {
-	fmt.Println(infoPrefix + message)
+	fmt.Fprintln(os.Stdout, infoPrefix+Redact(message))
 }
 
-// LogInfof info logging
+// LogInfof info logging to the console for output which does not belong to a scenario
 func LogInfof(format string, args ...interface{}) {
-	fmt.Printf(infoPrefix + fmt.Sprintf(format, args...))
+	fmt.Fprint(os.Stdout, infoPrefix+Redact(fmt.Sprintf(format, args...)))
+}
+
+// LogInfo info logging to the output of the running scenario
+func (c *ScenarioContext) LogInfo(message string) {
+	fmt.Fprintln(c.Output(), infoPrefix+Redact(message))
+}
+
+// LogInfof info logging to the output of the running scenario
+func (c *ScenarioContext) LogInfof(format string, args ...interface{}) {
+	fmt.Fprint(c.Output(), infoPrefix+Redact(fmt.Sprintf(format, args...)))
 }
 
 // Color avoids the color string if we should disable colors
 func Color(colorText string) string {
-	term := os.Getenv("TERM")
-	if strings.HasPrefix(term, "xterm") || term == "xterm" {
+	if ColorEnabled() {
 		return colorText
 	}
 	return ""
 }
+
+// ColorEnabled returns true if colours should be used. Colours are disabled by $NO_COLOR or the --no-colors
+// argument of godog otherwise $BDD_COLOR chooses whether to use colours, defaulting to auto which uses colours
+// if the standard output is a terminal
+func ColorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" || noColorsArgument() {
+		return false
+	}
+	mode := ColorMode()
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return isTerminal(os.Stdout) && os.Getenv("TERM") != "dumb"
+}
+
+// ColorMode returns the colour mode from $BDD_COLOR which defaults to auto
+func ColorMode() string {
+	mode := os.Getenv("BDD_COLOR")
+	switch mode {
+	case ColorAlways, ColorNever:
+		return mode
+	}
+	return ColorAuto
+}
+
+// noColorsArgument returns true if godog was run with --no-colors so that the scenario output matches its own
+func noColorsArgument() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		value := "true"
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value = name[:i], name[i+1:]
+		}
+		if name == "no-colors" {
+			enabled, err := strconv.ParseBool(value)
+			return err == nil && enabled
+		}
+	}
+	return false
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
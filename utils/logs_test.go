@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNoColorsArgument(t *testing.T) {
+	args := os.Args
+	defer func() {
+		os.Args = args
+	}()
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{}, false},
+		{[]string{"--no-colors"}, true},
+		{[]string{"-no-colors"}, true},
+		{[]string{"--format=progress", "--no-colors=true", "features"}, true},
+		{[]string{"--no-colors=false"}, false},
+		{[]string{"--", "--no-colors"}, false},
+		{[]string{"no-colors"}, false},
+	}
+	for _, test := range tests {
+		os.Args = append([]string{"godog"}, test.args...)
+		if actual := noColorsArgument(); actual != test.expected {
+			t.Errorf("noColorsArgument() with %v = %v, expected %v", test.args, actual, test.expected)
+		}
+	}
+}
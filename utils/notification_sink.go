@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SinkURLVariable is the name of the variable containing the URL of the notification sink for the current
+	// scenario which can be passed to a pipeline as ${SINK_URL}
+	SinkURLVariable = "SINK_URL"
+
+	defaultSinkAddress = ":0"
+
+	// the most of a request body the sink keeps
+	maxSinkBodySize = 1024 * 1024
+)
+
+// SinkRequest is a request received by the notification sink
+type SinkRequest struct {
+	Time   time.Time
+	Method string
+	// Path is the path of the request relative to the URL of the scenario
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// NotificationSink is a local HTTP server which captures the requests pipelines send so that scenarios can
+// verify them. Each scenario has its own URL so concurrent scenarios only see their own requests
+type NotificationSink struct {
+	// URL is the base URL of the sink which Jenkins uses to reach it
+	URL string
+
+	listener net.Listener
+	server   *http.Server
+	lock     sync.Mutex
+	requests map[string][]*SinkRequest
+}
+
+var (
+	sinkLock  sync.Mutex
+	sink      *NotificationSink
+	sinkUsers int
+	// sinkAddress is the address the sink first listened on so that it keeps the same port if it is restarted
+	sinkAddress string
+)
+
+// StartNotificationSink starts the notification sink listening on $BDD_SINK_ADDRESS, which defaults to a random
+// port on all interfaces. Each call must be matched by a call to StopNotificationSink; the sink keeps running
+// until the last user stops it so that concurrent features share it.
+//
+// Jenkins reaches the sink on the address of this machine which is used to connect to $BDD_JENKINS_URL.
+// Set $BDD_SINK_URL to the URL Jenkins can reach the sink on if that is not routable, e.g. through a NAT
+func StartNotificationSink() error {
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+	if sink != nil {
+		sinkUsers++
+		return nil
+	}
+	address := sinkAddress
+	if address == "" {
+		address = os.Getenv("BDD_SINK_ADDRESS")
+	}
+	if address == "" {
+		address = defaultSinkAddress
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("Failed to start the notification sink on %s due to %v", address, err)
+	}
+	sinkURL := os.Getenv("BDD_SINK_URL")
+	if sinkURL == "" {
+		sinkURL, err = defaultSinkURL(listener.Addr())
+		if err != nil {
+			listener.Close()
+			return err
+		}
+	}
+	s := &NotificationSink{
+		URL:      strings.TrimSuffix(sinkURL, "/"),
+		listener: listener,
+		requests: map[string][]*SinkRequest{},
+	}
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(listener)
+	sink = s
+	sinkUsers = 1
+	sinkAddress = listener.Addr().String()
+	LogInfof("notification sink listening on %s\n", s.URL)
+	return nil
+}
+
+// StopNotificationSink stops the notification sink once the last user which started it has stopped it
+func StopNotificationSink() {
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+	if sink == nil {
+		return
+	}
+	sinkUsers--
+	if sinkUsers <= 0 {
+		sink.server.Close()
+		sink = nil
+		sinkUsers = 0
+	}
+}
+
+// defaultSinkURL returns the URL of the sink on the address of this machine which Jenkins connections go out on
+func defaultSinkURL(addr net.Addr) (string, error) {
+	_, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", err
+	}
+	host := "127.0.0.1"
+	jenkinsURL, err := url.Parse(os.Getenv("BDD_JENKINS_URL"))
+	if err == nil && jenkinsURL.Hostname() != "" {
+		jenkinsPort := jenkinsURL.Port()
+		if jenkinsPort == "" {
+			jenkinsPort = "80"
+			if jenkinsURL.Scheme == "https" {
+				jenkinsPort = "443"
+			}
+		}
+		// dialing UDP sends no packets but picks the local address routed to Jenkins
+		conn, err := net.Dial("udp", net.JoinHostPort(jenkinsURL.Hostname(), jenkinsPort))
+		if err != nil {
+			return "", fmt.Errorf("Failed to find the address Jenkins can reach the notification sink on due to %v. Try setting $BDD_SINK_URL", err)
+		}
+		host, _, _ = net.SplitHostPort(conn.LocalAddr().String())
+		conn.Close()
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+// GetNotificationSink returns the running notification sink or nil if it has not been started
+func GetNotificationSink() *NotificationSink {
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+	return sink
+}
+
+// ScenarioURL returns the URL of the sink for the scenario
+func (s *NotificationSink) ScenarioURL(scenarioID string) string {
+	return s.URL + "/" + scenarioID
+}
+
+// ServeHTTP records the request against the scenario which is the first segment of its path
+func (s *NotificationSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSinkBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	scenarioID, path := splitSinkPath(r.URL.Path)
+	request := &SinkRequest{
+		Time:   time.Now(),
+		Method: r.Method,
+		Path:   path,
+		Query:  r.URL.Query(),
+		Header: r.Header,
+		Body:   body,
+	}
+	s.lock.Lock()
+	s.requests[scenarioID] = append(s.requests[scenarioID], request)
+	s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("{}"))
+}
+
+// Requests returns the requests the scenario has received so far
+func (s *NotificationSink) Requests(scenarioID string) []*SinkRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*SinkRequest{}, s.requests[scenarioID]...)
+}
+
+// WaitForRequest waits for the scenario to receive a request matching the function
+func (s *NotificationSink) WaitForRequest(c *ScenarioContext, scenarioID string, description string, timeout time.Duration, matches func(*SinkRequest) bool) (*SinkRequest, error) {
+	var result *SinkRequest
+	fn := func() (bool, error) {
+		for _, request := range s.Requests(scenarioID) {
+			if matches(request) {
+				result = request
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	err := c.PollPhase(TimeoutSink, 500*time.Millisecond, timeout, description, fn)
+	if err != nil {
+		return nil, fmt.Errorf("%v. The sink received: %s", err, s.describeRequests(scenarioID))
+	}
+	return result, nil
+}
+
+func (s *NotificationSink) describeRequests(scenarioID string) string {
+	requests := s.Requests(scenarioID)
+	if len(requests) == 0 {
+		return "no requests"
+	}
+	lines := []string{}
+	for _, request := range requests {
+		lines = append(lines, request.String())
+	}
+	return strings.Join(lines, ", ")
+}
+
+// clearSinkRequests removes the requests of the scenario once it has finished
+func clearSinkRequests(scenarioID string) {
+	s := GetNotificationSink()
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.requests, scenarioID)
+}
+
+// splitSinkPath splits a request path into the scenario ID and the path within the scenario
+func splitSinkPath(path string) (string, string) {
+	path = strings.TrimPrefix(path, "/")
+	i := strings.Index(path, "/")
+	if i < 0 {
+		return path, "/"
+	}
+	return path[:i], path[i:]
+}
+
+// JSONField returns the value of the field of the JSON body as text. Nested fields and array elements
+// are separated by dots such as `build.stages.0.name`
+func (r *SinkRequest) JSONField(field string) (string, error) {
+	var value interface{}
+	err := json.Unmarshal(r.Body, &value)
+	if err != nil {
+		return "", fmt.Errorf("the body of %s is not JSON: %v", r, err)
+	}
+	for _, name := range strings.Split(field, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, ok := v[name]
+			if !ok {
+				return "", fmt.Errorf("no field %s in the body of %s", field, r)
+			}
+			value = child
+		case []interface{}:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("no field %s in the body of %s", field, r)
+			}
+			value = v[i]
+		default:
+			return "", fmt.Errorf("no field %s in the body of %s", field, r)
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "null", nil
+	case float64, bool:
+		return fmt.Sprintf("%v", v), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (r *SinkRequest) String() string {
+	return r.Method + " " + r.Path
+}
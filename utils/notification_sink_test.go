@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestSinkRequestJSONField(t *testing.T) {
+	body := `{
+		"status": "SUCCESS",
+		"number": 42,
+		"duration": 1.5,
+		"passed": true,
+		"cause": null,
+		"build": {
+			"stages": [
+				{"name": "Build"},
+				{"name": "Deploy", "tags": ["prod", "eu"]}
+			]
+		},
+		"dotted.name": "not nested"
+	}`
+	tests := []struct {
+		field    string
+		expected string
+	}{
+		{"status", "SUCCESS"},
+		{"number", "42"},
+		{"duration", "1.5"},
+		{"passed", "true"},
+		{"cause", "null"},
+		{"build.stages.0.name", "Build"},
+		{"build.stages.1.tags.1", "eu"},
+		{"build.stages.1.tags", `["prod","eu"]`},
+		{"build.stages.0", `{"name":"Build"}`},
+	}
+	request := &SinkRequest{Method: "POST", Path: "/notify", Body: []byte(body)}
+	for _, test := range tests {
+		actual, err := request.JSONField(test.field)
+		if err != nil {
+			t.Errorf("JSONField(%q) failed: %v", test.field, err)
+		} else if actual != test.expected {
+			t.Errorf("JSONField(%q) = %q, expected %q", test.field, actual, test.expected)
+		}
+	}
+
+	for _, field := range []string{"missing", "status.length", "build.stages.2", "build.stages.-1", "build.stages.name", "dotted.name", ""} {
+		_, err := request.JSONField(field)
+		if err == nil {
+			t.Errorf("JSONField(%q) should have failed", field)
+		}
+	}
+}
+
+func TestSinkRequestJSONFieldWithoutJSON(t *testing.T) {
+	request := &SinkRequest{Method: "POST", Path: "/notify", Body: []byte("status=SUCCESS")}
+	_, err := request.JSONField("status")
+	if err == nil {
+		t.Errorf("JSONField of a form body should have failed")
+	}
+}
+
+func TestSplitSinkPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		scenario string
+		rest     string
+	}{
+		{"/abc-1/notify", "abc-1", "/notify"},
+		{"/abc-1/hooks/build/done", "abc-1", "/hooks/build/done"},
+		{"/abc-1", "abc-1", "/"},
+		{"/abc-1/", "abc-1", "/"},
+		{"/", "", "/"},
+	}
+	for _, test := range tests {
+		scenario, rest := splitSinkPath(test.path)
+		if scenario != test.scenario || rest != test.rest {
+			t.Errorf("splitSinkPath(%q) = %q, %q, expected %q, %q", test.path, scenario, rest, test.scenario, test.rest)
+		}
+	}
+}
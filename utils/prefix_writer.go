@@ -3,72 +3,94 @@ package utils
 import (
 	"bytes"
 	"io"
+	"strings"
+	"sync"
 )
 
+// PrefixWriter is a line buffered writer which writes each line to the underlying Writer with a prefix,
+// resetting any colour of the prefix at the end of the line. It records the output so that it can be
+// included in reports and is safe for concurrent writes
 type PrefixWriter struct {
-	Writer    io.Writer
-	buf       *bytes.Buffer
-	readLines string
-	prefix    string
-	persist   string
+	Writer  io.Writer
+	lock    sync.Mutex
+	buf     *bytes.Buffer
+	prefix  string
+	suffix  string
+	persist bytes.Buffer
 }
 
 func NewPrefixWriter(writer io.Writer, prefix string) *PrefixWriter {
 	streamer := &PrefixWriter{
-		Writer:  writer,
-		buf:     bytes.NewBuffer([]byte("")),
-		prefix:  prefix,
-		persist: "",
+		Writer: writer,
+		buf:    bytes.NewBuffer([]byte("")),
+		prefix: prefix,
+	}
+	if strings.Contains(prefix, "\x1b[") {
+		streamer.suffix = colorReset
 	}
 	return streamer
 }
 
+// Write writes all of the complete lines keeping any partial line until it is completed or the writer is closed
 func (l *PrefixWriter) Write(p []byte) (n int, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
 	if n, err = l.buf.Write(p); err != nil {
 		return
 	}
-	err = l.OutputLines()
+	err = l.outputLines()
 	return
 }
 
+// Close writes any remaining partial line
 func (l *PrefixWriter) Close() error {
-	l.Flush()
-	l.buf = bytes.NewBuffer([]byte(""))
-	return nil
+	return l.Flush()
 }
 
+// Flush writes any remaining partial line
 func (l *PrefixWriter) Flush() error {
-	var p []byte
-	if _, err := l.buf.Read(p); err != nil {
-		return err
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.buf.Len() == 0 {
+		return nil
 	}
+	line := l.buf.String()
+	l.buf.Reset()
+	return l.out(line + "\n")
+}
+
+// FlushRecord returns the output written so far without prefixes or colours and clears it
+func (l *PrefixWriter) FlushRecord() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
 
-	l.out(string(p))
-	return nil
+	buffer := l.persist.String()
+	l.persist.Reset()
+	return buffer
 }
 
-func (l *PrefixWriter) OutputLines() (err error) {
+func (l *PrefixWriter) outputLines() error {
 	for {
-		line, err := l.buf.ReadString('\n')
-		if err == io.EOF {
-			break
+		data := l.buf.Bytes()
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil
 		}
+		line := string(data[:i+1])
+		l.buf.Next(i + 1)
+		err := l.out(line)
 		if err != nil {
 			return err
 		}
-		l.out(line)
 	}
-	return nil
-}
-
-func (l *PrefixWriter) FlushRecord() string {
-	buffer := l.persist
-	l.persist = ""
-	return buffer
 }
 
-func (l *PrefixWriter) out(str string) (err error) {
-	str = l.prefix + str
+func (l *PrefixWriter) out(line string) (err error) {
+	line = Redact(line)
+	l.persist.WriteString(ansiEscapeCodes.ReplaceAllString(line, ""))
+	str := l.prefix + strings.TrimSuffix(line, "\n") + l.suffix + "\n"
 	_, err = l.Writer.Write([]byte(str))
 	return err
 }
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	redactedText = "****"
+
+	// secrets shorter than this are not redacted as they would mask too much unrelated output
+	minSecretLength = 4
+)
+
+// secretEnvVars are the environment variables which contain secrets
+var secretEnvVars = []string{
+	"BDD_JENKINS_TOKEN",
+	"BDD_JENKINS_BEARER_TOKEN",
+	"GITHUB_PASSWORD",
+	"GITHUB_TOKEN",
+	"BDD_GIT_SSH_KEY_PASSWORD",
+	"BDD_GITHUB_WEBHOOK_SECRET",
+}
+
+var (
+	secretsLock sync.Mutex
+	secrets     = map[string]bool{}
+
+	urlUserInfo   = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*)://([^/@\s:]*)(:[^/@\s]*)?@`)
+	secretQueries = regexp.MustCompile(`(?i)\b((?:access_)?token|password|secret)=([^&\s"']+)`)
+)
+
+// AddSecret registers a secret which is not in one of the secret environment variables, such as a token
+// read from a file, so that it is redacted from all output
+func AddSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	secrets[secret] = true
+}
+
+// Redact masks all of the known secrets, the passwords in URLs and the tokens in query strings in the text
+func Redact(text string) string {
+	for _, secret := range knownSecrets() {
+		text = strings.Replace(text, secret, redactedText, -1)
+	}
+	text = urlUserInfo.ReplaceAllStringFunc(text, redactUserInfo)
+	return secretQueries.ReplaceAllString(text, "${1}="+redactedText)
+}
+
+// RedactError returns an error with all of the secrets masked in its message
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	text := err.Error()
+	redacted := Redact(text)
+	if redacted == text {
+		return err
+	}
+	return errors.New(redacted)
+}
+
+// redactUserInfo masks the password of the user info of a URL or the user name of an http URL
+// which is a token when there is no password
+func redactUserInfo(userInfo string) string {
+	groups := urlUserInfo.FindStringSubmatch(userInfo)
+	scheme, user, password := groups[1], groups[2], groups[3]
+	if password != "" {
+		return scheme + "://" + user + ":" + redactedText + "@"
+	}
+	if strings.HasPrefix(strings.ToLower(scheme), "http") && user != "" {
+		return scheme + "://" + redactedText + "@"
+	}
+	return userInfo
+}
+
+// knownSecrets returns the secrets longest first so that a secret containing another one is masked whole
+func knownSecrets() []string {
+	answer := []string{}
+	for _, name := range secretEnvVars {
+		value := os.Getenv(name)
+		if len(value) >= minSecretLength {
+			answer = append(answer, value)
+		}
+	}
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+	for secret := range secrets {
+		answer = append(answer, secret)
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		if len(answer[i]) != len(answer[j]) {
+			return len(answer[i]) > len(answer[j])
+		}
+		return answer[i] < answer[j]
+	})
+	return answer
+}
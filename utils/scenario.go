@@ -0,0 +1,374 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DATA-DOG/godog"
+	"github.com/DATA-DOG/godog/gherkin"
+)
+
+const (
+	// ScenarioIDVariable is the name of the variable containing the unique ID of the current scenario
+	// which can be used in expressions as ${SCENARIO_ID} to create resource names which do not clash
+	// when scenarios run concurrently
+	ScenarioIDVariable = "SCENARIO_ID"
+
+	defaultWorkDir = "work"
+)
+
+// Scenario is the state of a running scenario so that scenarios can run concurrently
+// without sharing work directories or resource names
+type Scenario struct {
+	// Feature is the name of the feature of the scenario
+	Feature string
+	// Name is the name of the scenario in the feature file
+	Name string
+	// ID is unique for each scenario in a test run
+	ID string
+	// WorkDir is the directory the scenario uses for its git clones
+	WorkDir string
+	// ReportName is the unique relative path of the log and other files of the scenario
+	ReportName string
+	// Log captures all of the output of the scenario
+	Log *ScenarioLog
+	// Tags are the tags of the feature and the scenario
+	Tags []string
+
+	step     *gherkin.Step
+	waits    []*wait
+	cleanups []func()
+}
+
+var (
+	scenarioLock  sync.Mutex
+	scenarioCount int
+
+	resourceLock     sync.Mutex
+	resourceReleased = sync.NewCond(&resourceLock)
+	resourceOwners   = map[string]*Scenario{}
+
+	suiteLock     sync.Mutex
+	suiteContexts = map[*godog.Suite]*ScenarioContext{}
+
+	runID = strconv.FormatInt(time.Now().Unix(), 36)
+)
+
+// ScenarioContext carries the running scenario of a godog suite to the steps and helpers which need it.
+// godog creates a suite for each feature when running features concurrently, so each suite has its own
+// context and only runs one scenario at a time
+type ScenarioContext struct {
+	suite    *godog.Suite
+	lock     sync.Mutex
+	feature  *gherkin.Feature
+	scenario *Scenario
+}
+
+// WorkDir returns the base work directory from $WORK_DIR which defaults to `work`
+func WorkDir() string {
+	dir := os.Getenv("WORK_DIR")
+	if len(dir) == 0 {
+		dir = defaultWorkDir
+	}
+	return dir
+}
+
+// SuiteContext returns the ScenarioContext of the suite. The first call for a suite registers the hooks which
+// start and end its scenarios, so feature contexts should call it before registering their own hooks, should
+// register their steps with Step and should use AddCleanup rather than AfterScenario for anything which needs
+// the scenario
+func SuiteContext(s *godog.Suite) *ScenarioContext {
+	suiteLock.Lock()
+	defer suiteLock.Unlock()
+	c := suiteContexts[s]
+	if c != nil {
+		return c
+	}
+	c = &ScenarioContext{suite: s}
+	suiteContexts[s] = c
+
+	s.BeforeFeature(c.startFeature)
+	s.AfterFeature(c.endFeature)
+	s.BeforeScenario(func(scenario interface{}) {
+		c.startScenario(scenario)
+	})
+	s.BeforeStep(c.startStep)
+	s.AfterStep(c.endStep)
+	s.AfterScenario(func(scenario interface{}, err error) {
+		c.endScenario(err)
+	})
+	s.AfterSuite(func() {
+		suiteLock.Lock()
+		defer suiteLock.Unlock()
+		delete(suiteContexts, s)
+	})
+	return c
+}
+
+// Step registers the step with the suite of the context. godog prints the errors steps return as they are so
+// the secrets in the errors of the step are redacted first
+func (c *ScenarioContext) Step(expr interface{}, stepFunc interface{}) {
+	c.suite.Step(expr, redactStepErrors(stepFunc))
+}
+
+// redactStepErrors wraps a step function returning an error with a function of the same type which redacts it
+func redactStepErrors(stepFunc interface{}) interface{} {
+	fn := reflect.ValueOf(stepFunc)
+	t := fn.Type()
+	if t.Kind() != reflect.Func || t.NumOut() != 1 || t.Out(0) != errorType {
+		return stepFunc
+	}
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		results := fn.Call(args)
+		if err, ok := results[0].Interface().(error); ok && err != nil {
+			redacted := RedactError(err)
+			results[0] = reflect.ValueOf(&redacted).Elem()
+		}
+		return results
+	}).Interface()
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Scenario returns the running scenario or nil if there is none
+func (c *ScenarioContext) Scenario() *Scenario {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.scenario
+}
+
+// startFeature records the feature so that the logs of its scenarios are grouped by feature
+func (c *ScenarioContext) startFeature(feature *gherkin.Feature) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.feature = feature
+}
+
+func (c *ScenarioContext) endFeature(feature *gherkin.Feature) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.feature = nil
+}
+
+// startScenario creates the Scenario for the godog scenario and makes it the running scenario of the context
+func (c *ScenarioContext) startScenario(scenario interface{}) {
+	name := ScenarioName(scenario)
+
+	scenarioLock.Lock()
+	scenarioCount++
+	id := runID + "-" + strconv.Itoa(scenarioCount)
+	scenarioLock.Unlock()
+
+	answer := &Scenario{
+		Name:    name,
+		ID:      id,
+		WorkDir: filepath.Join(WorkDir(), id),
+	}
+	c.lock.Lock()
+	if c.feature != nil {
+		answer.Feature = c.feature.Name
+		answer.Tags = tagNames(c.feature.Tags)
+	}
+	c.lock.Unlock()
+
+	answer.Tags = append(answer.Tags, ScenarioTags(scenario)...)
+
+	answer.ReportName = UniqueReportName(answer.Feature, name)
+	log, err := CreateScenarioLog(answer.ReportName, answer.Label())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: logging scenario %s to the console as %v\n", name, err)
+	} else {
+		answer.Log = log
+	}
+
+	c.lock.Lock()
+	c.scenario = answer
+	c.lock.Unlock()
+
+	c.RecordEvent(&Event{
+		Type: EventScenarioStarted,
+	})
+}
+
+// endScenario runs the cleanups of the running scenario and ends it, removing its work directory if it passed
+// unless $BDD_KEEP_WORK_DIR is true
+func (c *ScenarioContext) endScenario(err error) {
+	scenario := c.Scenario()
+	if scenario == nil {
+		return
+	}
+	scenario.runCleanups()
+
+	event := &Event{
+		Type:   EventScenarioFinished,
+		Result: "passed",
+	}
+	if err != nil {
+		event.Result = "failed"
+		event.Error = err.Error()
+	}
+	c.RecordEvent(event)
+
+	c.lock.Lock()
+	c.scenario = nil
+	c.lock.Unlock()
+
+	closeCassettes(scenario.ReportName)
+	clearSinkRequests(scenario.ID)
+	if err == nil && os.Getenv("BDD_KEEP_WORK_DIR") != "true" {
+		os.RemoveAll(scenario.WorkDir)
+	}
+	result := "passed"
+	if err != nil {
+		result = fmt.Sprintf("failed: %v", RedactError(err))
+	}
+	if scenario.Log != nil {
+		fmt.Fprintf(scenario.Log, "%sscenario %s\n", infoPrefix, result)
+		scenario.Log.Close()
+		result += " see " + scenario.Log.FileName
+	}
+	fmt.Printf("%s%sscenario %s %s\n", infoPrefix, scenario.Label(), scenario.Name, result)
+}
+
+// AddCleanup registers a function to run at the end of the running scenario before its log is closed, such as
+// restoring something the scenario changed. Cleanups run in the reverse order they were added.
+// It returns false if there is no running scenario
+func (c *ScenarioContext) AddCleanup(fn func()) bool {
+	scenario := c.Scenario()
+	if scenario == nil {
+		return false
+	}
+	scenarioLock.Lock()
+	defer scenarioLock.Unlock()
+	scenario.cleanups = append(scenario.cleanups, fn)
+	return true
+}
+
+func (s *Scenario) runCleanups() {
+	scenarioLock.Lock()
+	cleanups := s.cleanups
+	s.cleanups = nil
+	scenarioLock.Unlock()
+
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
+
+// Output returns the writer for the output of the running scenario or the console if there is no scenario
+func (c *ScenarioContext) Output() io.Writer {
+	scenario := c.Scenario()
+	if scenario == nil || scenario.Log == nil {
+		return os.Stdout
+	}
+	return scenario.Log
+}
+
+// ErrorOutput returns the writer for the error output of the running scenario or the console if there is no scenario
+func (c *ScenarioContext) ErrorOutput() io.Writer {
+	scenario := c.Scenario()
+	if scenario == nil || scenario.Log == nil {
+		return os.Stderr
+	}
+	return scenario.Log
+}
+
+// LockResource waits until no other scenario holds the named resource, such as a fork which scenarios reset,
+// then holds it until the end of the running scenario so that concurrent features sharing it take turns
+func (c *ScenarioContext) LockResource(name string) {
+	scenario := c.Scenario()
+	if scenario == nil {
+		return
+	}
+	resourceLock.Lock()
+	defer resourceLock.Unlock()
+	owner := resourceOwners[name]
+	if owner == scenario {
+		return
+	}
+	if owner != nil {
+		c.LogInfof("waiting for scenario %s to finish with %s\n", owner.ID, name)
+	}
+	for resourceOwners[name] != nil {
+		resourceReleased.Wait()
+	}
+	resourceOwners[name] = scenario
+	c.AddCleanup(func() {
+		resourceLock.Lock()
+		defer resourceLock.Unlock()
+		delete(resourceOwners, name)
+		resourceReleased.Broadcast()
+	})
+}
+
+// ScenarioName returns the name of the scenario or scenario outline passed to the godog scenario hooks
+func ScenarioName(scenario interface{}) string {
+	switch s := scenario.(type) {
+	case *gherkin.Scenario:
+		return s.Name
+	case *gherkin.ScenarioOutline:
+		return s.Name
+	}
+	return ""
+}
+
+// ScenarioTags returns the tag names of the godog scenario
+func ScenarioTags(scenario interface{}) []string {
+	switch s := scenario.(type) {
+	case *gherkin.Scenario:
+		return tagNames(s.Tags)
+	case *gherkin.ScenarioOutline:
+		return tagNames(s.Tags)
+	}
+	return nil
+}
+
+func tagNames(tags []*gherkin.Tag) []string {
+	answer := []string{}
+	for _, tag := range tags {
+		answer = append(answer, tag.Name)
+	}
+	return answer
+}
+
+// Variables returns the variables of the scenario which can be used in expressions
+func (s *Scenario) Variables() map[string]string {
+	answer := map[string]string{
+		ScenarioIDVariable: s.ID,
+	}
+	if sink := GetNotificationSink(); sink != nil {
+		answer[SinkURLVariable] = sink.ScenarioURL(s.ID)
+	}
+	return answer
+}
+
+func (s *Scenario) setCurrentStep(step *gherkin.Step) {
+	scenarioLock.Lock()
+	defer scenarioLock.Unlock()
+	s.step = step
+}
+
+// currentStep returns the text and line of the current step or an empty string if there is none
+func (s *Scenario) currentStep() (string, int) {
+	scenarioLock.Lock()
+	step := s.step
+	scenarioLock.Unlock()
+	if step == nil {
+		return "", 0
+	}
+	return step.Text, stepLine(step)
+}
+
+// Label returns the short label used to attribute output to the scenario
+func (s *Scenario) Label() string {
+	return fmt.Sprintf("[%s] ", s.ID)
+}
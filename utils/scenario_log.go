@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultReportsDir = "reports"
+
+var (
+	invalidFileNameCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+	ansiEscapeCodes           = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+	reportNameLock sync.Mutex
+	reportNames    = map[string]bool{}
+)
+
+// ScenarioLog is the buffered sink for all of the output of a scenario which is written to
+// reports/<feature>/<scenario>.log and also to the console when $BDD_VERBOSE is true
+type ScenarioLog struct {
+	FileName string
+
+	lock    sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	console io.Writer
+}
+
+// Verbose returns true if all of the output of the scenarios should be written to the console via $BDD_VERBOSE
+func Verbose() bool {
+	return os.Getenv("BDD_VERBOSE") == "true"
+}
+
+// ReportsDir returns the directory that reports are written to from $BDD_REPORTS_DIR which defaults to `reports`
+func ReportsDir() string {
+	dir := os.Getenv("BDD_REPORTS_DIR")
+	if dir == "" {
+		dir = defaultReportsDir
+	}
+	return dir
+}
+
+// CreateScenarioLog creates the log file for the scenario with the given report name
+func CreateScenarioLog(reportName string, label string) (*ScenarioLog, error) {
+	fileName := filepath.Join(ReportsDir(), reportName+".log")
+	dir := filepath.Dir(fileName)
+	err := os.MkdirAll(dir, 0770)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the reports directory %s due to %v", dir, err)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the scenario log %s due to %v", fileName, err)
+	}
+	answer := &ScenarioLog{
+		FileName: fileName,
+		file:     file,
+		writer:   bufio.NewWriter(file),
+	}
+	if Verbose() {
+		answer.console = NewPrefixWriter(os.Stdout, label)
+	}
+	return answer, nil
+}
+
+// Write writes the redacted output to the log file without any colour codes and to the console if verbose
+func (l *ScenarioLog) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	text := Redact(string(p))
+	if l.console != nil {
+		l.console.Write([]byte(text))
+	}
+	if l.writer == nil {
+		return len(p), nil
+	}
+	_, err := l.writer.WriteString(ansiEscapeCodes.ReplaceAllString(text, ""))
+	return len(p), err
+}
+
+// Close flushes the log and closes the log file
+func (l *ScenarioLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.writer == nil {
+		return nil
+	}
+	if closer, ok := l.console.(io.Closer); ok {
+		closer.Close()
+	}
+	err := l.writer.Flush()
+	l.writer = nil
+	closeErr := l.file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SanitizeFileName converts the text into a lower case name which is safe to use as a file name
+func SanitizeFileName(text string) string {
+	answer := strings.Trim(invalidFileNameCharacters.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if answer == "" {
+		answer = "unnamed"
+	}
+	return answer
+}
+
+// UniqueReportName returns the relative path of the files of the scenario such as `<feature>/<scenario>`
+// which has not been used in this run as each example of a scenario outline has the same name
+func UniqueReportName(feature string, scenario string) string {
+	reportNameLock.Lock()
+	defer reportNameLock.Unlock()
+
+	path := filepath.Join(SanitizeFileName(feature), SanitizeFileName(scenario))
+	answer := path
+	for i := 2; reportNames[answer]; i++ {
+		answer = path + "-" + strconv.Itoa(i)
+	}
+	reportNames[answer] = true
+	return answer
+}
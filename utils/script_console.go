@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultScriptsDir        = "resources/scripts"
+	defaultProtectedProfiles = "shared,production"
+)
+
+// scriptErrorPattern matches the exception the script console prints instead of failing the request
+var scriptErrorPattern = regexp.MustCompile(`^(?:[a-z][\w$]*\.)+[A-Z][\w$]*(?:Exception|Error)\b`)
+
+// ScriptError is returned when a script run by the script console throws an exception
+type ScriptError struct {
+	Output string
+}
+
+func (e *ScriptError) Error() string {
+	return "the Jenkins script failed: " + strings.TrimSpace(e.Output)
+}
+
+// RunScript runs the Groovy script in the Jenkins script console returning its output. As the script console
+// responds successfully when the script throws an exception a ScriptError is returned if the output is a stack trace
+func (a *JenkinsAPI) RunScript(script string) (string, error) {
+	data, err := a.PostForm("scriptText", url.Values{"script": {script}})
+	if err != nil {
+		return "", fmt.Errorf("Failed to run the Jenkins script due to %v", err)
+	}
+	output := string(data)
+	if scriptErrorPattern.MatchString(strings.TrimSpace(output)) {
+		return output, &ScriptError{Output: output}
+	}
+	return output, nil
+}
+
+// Profile returns the name of the Jenkins instance profile the tests run against from $BDD_PROFILE
+func Profile() string {
+	return os.Getenv("BDD_PROFILE")
+}
+
+// IsProtectedProfile returns true if the profile is one of the comma separated $BDD_PROTECTED_PROFILES which
+// default to shared and production. Without a profile the Jenkins instance is treated as protected as nothing
+// says it is safe to run any script against it
+func IsProtectedProfile() bool {
+	profile := Profile()
+	if profile == "" {
+		return true
+	}
+	protected := os.Getenv("BDD_PROTECTED_PROFILES")
+	if protected == "" {
+		protected = defaultProtectedProfiles
+	}
+	for _, name := range strings.Split(protected, ",") {
+		if strings.TrimSpace(name) == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// ScriptsDir returns the directory of the safe listed scripts from $BDD_SCRIPTS_DIR which defaults to `resources/scripts`
+func ScriptsDir() string {
+	dir := os.Getenv("BDD_SCRIPTS_DIR")
+	if dir == "" {
+		dir = defaultScriptsDir
+	}
+	return dir
+}
+
+// CheckInlineScriptAllowed returns an error if scripts which are not in the scripts directory may not be run
+// as the tests are running against a protected profile
+func CheckInlineScriptAllowed() error {
+	if IsProtectedProfile() {
+		if Profile() == "" {
+			return fmt.Errorf("inline Jenkins scripts are not allowed without a profile. Set $BDD_PROFILE to a profile which is not protected, such as local, or use a script from %s instead", ScriptsDir())
+		}
+		return fmt.Errorf("inline Jenkins scripts are not allowed with the protected profile %s. Use a script from %s instead", Profile(), ScriptsDir())
+	}
+	return nil
+}
+
+// LoadScript returns the script with the relative file name in the scripts directory
+func LoadScript(name string) (string, error) {
+	dir := ScriptsDir()
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || isOutside(clean) {
+		return "", fmt.Errorf("the Jenkins script %s is not inside the scripts directory %s", name, dir)
+	}
+	fileName := filepath.Join(dir, clean)
+
+	// lets not follow symlinks out of the scripts directory
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("Failed to find the scripts directory %s due to %v", dir, err)
+	}
+	realFile, err := filepath.EvalSymlinks(fileName)
+	if err != nil {
+		return "", fmt.Errorf("Failed to find the Jenkins script %s due to %v", fileName, err)
+	}
+	rel, err := filepath.Rel(realDir, realFile)
+	if err != nil || isOutside(rel) {
+		return "", fmt.Errorf("the Jenkins script %s is not inside the scripts directory %s", name, dir)
+	}
+	data, err := ioutil.ReadFile(realFile)
+	if err != nil {
+		return "", fmt.Errorf("Failed to load the Jenkins script %s due to %v", fileName, err)
+	}
+	return string(data), nil
+}
+
+func isOutside(relativePath string) bool {
+	return relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadScriptStaysInsideTheScriptsDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "godog-scripts-")
+	if err != nil {
+		t.Fatalf("failed to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	scriptsDir := filepath.Join(dir, "scripts")
+	writeTestFile(t, filepath.Join(scriptsDir, "hello.groovy"), "println 'hello'")
+	writeTestFile(t, filepath.Join(scriptsDir, "nodes", "labels.groovy"), "println 'labels'")
+	writeTestFile(t, filepath.Join(dir, "outside.groovy"), "println 'outside'")
+	err = os.Symlink(filepath.Join(dir, "outside.groovy"), filepath.Join(scriptsDir, "escape.groovy"))
+	if err != nil {
+		t.Fatalf("failed to create a symlink: %v", err)
+	}
+	err = os.Symlink(filepath.Join(scriptsDir, "hello.groovy"), filepath.Join(scriptsDir, "alias.groovy"))
+	if err != nil {
+		t.Fatalf("failed to create a symlink: %v", err)
+	}
+
+	scriptsDirEnv, hadScriptsDir := os.LookupEnv("BDD_SCRIPTS_DIR")
+	defer func() {
+		if hadScriptsDir {
+			os.Setenv("BDD_SCRIPTS_DIR", scriptsDirEnv)
+		} else {
+			os.Unsetenv("BDD_SCRIPTS_DIR")
+		}
+	}()
+	os.Setenv("BDD_SCRIPTS_DIR", scriptsDir)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"hello.groovy", "println 'hello'"},
+		{"nodes/labels.groovy", "println 'labels'"},
+		{"nodes/../hello.groovy", "println 'hello'"},
+		{"alias.groovy", "println 'hello'"},
+		{"../outside.groovy", ""},
+		{"nodes/../../outside.groovy", ""},
+		{"..", ""},
+		{filepath.Join(dir, "outside.groovy"), ""},
+		{filepath.Join(scriptsDir, "hello.groovy"), ""},
+		{"escape.groovy", ""},
+		{"missing.groovy", ""},
+	}
+	for _, test := range tests {
+		actual, err := LoadScript(test.name)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("LoadScript(%q) = %q, expected an error", test.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LoadScript(%q) failed: %v", test.name, err)
+		} else if actual != test.expected {
+			t.Errorf("LoadScript(%q) = %q, expected %q", test.name, actual, test.expected)
+		}
+	}
+}
+
+func writeTestFile(t *testing.T, fileName string, text string) {
+	err := os.MkdirAll(filepath.Dir(fileName), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(fileName, []byte(text), 0644)
+	}
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", fileName, err)
+	}
+}
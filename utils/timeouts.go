@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fabric8-jenkins/golang-jenkins"
+)
+
+// the phases of a scenario which have a configurable timeout
+const (
+	// TimeoutBuildCreated is the time to wait for Jenkins to create a job or its first build
+	TimeoutBuildCreated = "build-created"
+	// TimeoutBuildStart is the time to wait for a triggered build to start
+	TimeoutBuildStart = "build-start"
+	// TimeoutBuild is the time to wait for a build to complete
+	TimeoutBuild = "build"
+	// TimeoutForkReady is the time to wait for a new fork to be ready
+	TimeoutForkReady = "fork-ready"
+	// TimeoutStatusCheck is the time to wait for the status checks of a commit
+	TimeoutStatusCheck = "status-check"
+	// TimeoutJobDeleted is the time to wait for a deleted job and all of its children to be removed
+	TimeoutJobDeleted = "job-deleted"
+	// TimeoutSink is the time to wait for a pipeline to call the notification sink
+	TimeoutSink = "sink"
+	// TimeoutScan is the time to wait for the scan of an organisation or multibranch project to finish
+	TimeoutScan = "scan"
+
+	timeoutTagPrefix = "@timeout-"
+)
+
+type timeoutDefinition struct {
+	envVar       string
+	defaultValue time.Duration
+}
+
+var timeoutDefinitions = map[string]timeoutDefinition{
+	TimeoutBuildCreated: {"BDD_TIMEOUT_BUILD_CREATED", 50 * time.Second},
+	TimeoutBuildStart:   {"BDD_TIMEOUT_BUILD_START", 20 * time.Second},
+	TimeoutBuild:        {"BDD_TIMEOUT_BUILD", 40 * time.Minute},
+	TimeoutForkReady:    {"BDD_FORK_READY_TIMEOUT", 5 * time.Minute},
+	TimeoutStatusCheck:  {"BDD_TIMEOUT_STATUS_CHECK", 20 * time.Minute},
+	TimeoutJobDeleted:   {"BDD_TIMEOUT_JOB_DELETED", 2 * time.Minute},
+	TimeoutSink:         {"BDD_TIMEOUT_SINK", 5 * time.Minute},
+	TimeoutScan:         {"BDD_TIMEOUT_SCAN", 15 * time.Minute},
+}
+
+// wait is the time a scenario spent waiting in a phase
+type wait struct {
+	phase    string
+	timeout  time.Duration
+	used     time.Duration
+	timedOut bool
+}
+
+var waitLock sync.Mutex
+
+// Timeout returns the timeout of the phase from a `@timeout-<phase>=<duration>` tag of the running scenario or
+// its feature, otherwise from its environment variable such as $BDD_TIMEOUT_BUILD or its default.
+// The timeout is multiplied by $BDD_TIMEOUT_MULTIPLIER for slow clusters
+func (c *ScenarioContext) Timeout(phase string) time.Duration {
+	definition, ok := timeoutDefinitions[phase]
+	if !ok {
+		panic(fmt.Sprintf("unknown timeout phase %s", phase))
+	}
+	answer := definition.defaultValue
+	text := os.Getenv(definition.envVar)
+	if text != "" {
+		d, err := time.ParseDuration(text)
+		if err == nil {
+			answer = d
+		} else {
+			c.LogInfof("WARNING: ignoring invalid $%s %s due to %v\n", definition.envVar, text, err)
+		}
+	}
+	if d, ok := c.tagTimeout(phase); ok {
+		answer = d
+	}
+	return time.Duration(float64(answer) * TimeoutMultiplier())
+}
+
+// TimeoutMultiplier returns the factor all configured timeouts are multiplied by from $BDD_TIMEOUT_MULTIPLIER
+// which defaults to 1
+func TimeoutMultiplier() float64 {
+	text := os.Getenv("BDD_TIMEOUT_MULTIPLIER")
+	if text == "" {
+		return 1
+	}
+	multiplier, err := strconv.ParseFloat(text, 64)
+	if err != nil || multiplier <= 0 {
+		LogInfof("WARNING: ignoring invalid $BDD_TIMEOUT_MULTIPLIER %s\n", text)
+		return 1
+	}
+	return multiplier
+}
+
+// tagTimeout returns the timeout of the phase from the last matching tag of the running scenario
+// so that scenario tags override feature tags
+func (c *ScenarioContext) tagTimeout(phase string) (time.Duration, bool) {
+	scenario := c.Scenario()
+	if scenario == nil {
+		return 0, false
+	}
+	prefix := timeoutTagPrefix + phase + "="
+	var answer time.Duration
+	found := false
+	for _, tag := range scenario.Tags {
+		if !strings.HasPrefix(tag, prefix) {
+			continue
+		}
+		text := strings.TrimPrefix(tag, prefix)
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			c.LogInfof("WARNING: ignoring invalid timeout tag %s due to %v\n", tag, err)
+			continue
+		}
+		answer = d
+		found = true
+	}
+	return answer, found
+}
+
+// PollPhase polls the function like Poll recording the time used in the phase against the time budget
+// of the running scenario. If the timeout expires the error reports how much of its budget each phase used
+func (c *ScenarioContext) PollPhase(phase string, pollPeriod time.Duration, timeout time.Duration, timeoutFailureMessage string, fn gojenkins.ConditionFunc) error {
+	failed := false
+	start := time.Now()
+	err := Poll(pollPeriod, timeout, timeoutFailureMessage, func() (bool, error) {
+		ok, err := fn()
+		if err != nil {
+			failed = true
+		}
+		return ok, err
+	})
+	timedOut := err != nil && !failed
+	scenario := c.Scenario()
+	if scenario == nil {
+		return err
+	}
+	scenario.addWait(&wait{
+		phase:    phase,
+		timeout:  timeout,
+		used:     time.Since(start),
+		timedOut: timedOut,
+	})
+	if timedOut {
+		return fmt.Errorf("%v. Time budget used: %s", err, scenario.TimeBudgetReport())
+	}
+	return err
+}
+
+// TimeBudgetReport returns how much of its timeout each wait of the scenario used
+func (s *Scenario) TimeBudgetReport() string {
+	waitLock.Lock()
+	defer waitLock.Unlock()
+
+	lines := []string{}
+	for _, w := range s.waits {
+		line := fmt.Sprintf("%s %s of %s", w.phase, roundDuration(w.used), w.timeout)
+		if w.timedOut {
+			line += " (timed out)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, ", ")
+}
+
+func (s *Scenario) addWait(w *wait) {
+	waitLock.Lock()
+	defer waitLock.Unlock()
+	s.waits = append(s.waits, w)
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	return d - d%(100*time.Millisecond)
+}
@@ -13,17 +13,30 @@ import (
 	"github.com/fabric8-jenkins/golang-jenkins"
 )
 
-func GetJenkinsClient() (*gojenkins.Jenkins, error) {
+// GetJenkinsClient creates a Jenkins client from the BDD_JENKINS_* env vars which records its requests against
+// the running scenario of the context
+func GetJenkinsClient(c *ScenarioContext) (*gojenkins.Jenkins, error) {
+	url, auth, err := jenkinsURLAndAuth()
+	if err != nil {
+		return nil, err
+	}
+	jenkins := gojenkins.NewJenkins(auth, url)
+	jenkins.SetHTTPClient(newJenkinsHTTPClient(c))
+	return jenkins, nil
+}
+
+// jenkinsURLAndAuth returns the Jenkins URL and credentials from the BDD_JENKINS_* env vars
+func jenkinsURLAndAuth() (string, *gojenkins.Auth, error) {
 	url := os.Getenv("BDD_JENKINS_URL")
 	if url == "" {
-		return nil, errors.New("no BDD_JENKINS_URL env var set. Try running this command first:\n\n  eval $(gofabric8 bdd-env)\n")
+		return "", nil, errors.New("no BDD_JENKINS_URL env var set. Try running this command first:\n\n  eval $(gofabric8 bdd-env)\n")
 	}
 	username := os.Getenv("BDD_JENKINS_USERNAME")
 	token := os.Getenv("BDD_JENKINS_TOKEN")
 
 	bearerToken := os.Getenv("BDD_JENKINS_BEARER_TOKEN")
 	if bearerToken == "" && (token == "" || username == "") {
-		return nil, errors.New("no BDD_JENKINS_TOKEN or BDD_JENKINS_BEARER_TOKEN && BDD_JENKINS_USERNAME env var set")
+		return "", nil, errors.New("no BDD_JENKINS_TOKEN or BDD_JENKINS_BEARER_TOKEN && BDD_JENKINS_USERNAME env var set")
 	}
 
 	auth := &gojenkins.Auth{
@@ -31,18 +44,27 @@ func GetJenkinsClient() (*gojenkins.Jenkins, error) {
 		ApiToken:    token,
 		BearerToken: bearerToken,
 	}
-	jenkins := gojenkins.NewJenkins(auth, url)
+	return url, auth, nil
+}
 
+// newJenkinsHTTPClient creates the http.Client for talking to Jenkins which records events and cassettes
+func newJenkinsHTTPClient(c *ScenarioContext) *http.Client {
 	// handle insecure TLS for minishift
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	return &http.Client{
+		Transport: &EventTransport{
+			Type:    EventJenkinsRequest,
+			Context: c,
+			Transport: &CassetteTransport{
+				Name:    "jenkins",
+				Context: c,
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				},
+			},
 		},
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}}
-	jenkins.SetHTTPClient(httpClient)
-	return jenkins, nil
 }
 
 func GetFileAsString(path string) (string, error) {
@@ -66,7 +88,7 @@ func RetryAfter(attempts int, callback func() error, d time.Duration) (err error
 			return nil
 		}
 		m.Collect(err)
-		time.Sleep(d)
+		Sleep(d)
 	}
 	return m.ToError()
 }
@@ -86,5 +108,18 @@ func (m MultiError) ToError() error {
 	for _, err := range m.Errors {
 		errStrings = append(errStrings, err.Error())
 	}
-	return fmt.Errorf(strings.Join(errStrings, "\n"))
+	return errors.New(strings.Join(errStrings, "\n"))
+}
+
+// ParseDuration returns the duration for an amount of time units such as `5 minutes` used in feature steps
+func ParseDuration(amount int, unit string) (time.Duration, error) {
+	switch strings.TrimSuffix(strings.ToLower(unit), "s") {
+	case "second":
+		return time.Duration(amount) * time.Second, nil
+	case "minute":
+		return time.Duration(amount) * time.Minute, nil
+	case "hour":
+		return time.Duration(amount) * time.Hour, nil
+	}
+	return 0, fmt.Errorf("Unknown time unit %s. Expected seconds, minutes or hours", unit)
 }
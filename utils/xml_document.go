@@ -0,0 +1,496 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// XMLDocument is an editable XML document such as the config.xml of a Jenkins job which keeps the
+// comments, whitespace and declaration of the original document so that it can be written back
+type XMLDocument struct {
+	// Declaration is the XML declaration which is kept as text as Jenkins uses XML 1.1
+	Declaration string
+	Root        *XMLElement
+	// the whitespace or comments around the root element
+	before []interface{}
+	after  []interface{}
+}
+
+// XMLElement is an element of an XMLDocument whose children are *XMLElement, xml.CharData,
+// xml.Comment, xml.ProcInst or xml.Directive values
+type XMLElement struct {
+	Name     string
+	Attr     []xml.Attr
+	Children []interface{}
+}
+
+// the escapers only escape what they must so that whitespace and quotes are written back as they were
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;")
+)
+
+// xmlStep is a step of a path such as `name[child='value']` or `//*[@attr='value'][2]`
+type xmlStep struct {
+	name       string
+	descendant bool
+	attribute  string
+	predicates []xmlPredicate
+}
+
+// xmlPredicate matches a child element's text or an attribute with a value or selects by 1 based index
+type xmlPredicate struct {
+	child     string
+	attribute string
+	value     string
+	index     int
+}
+
+// ParseXMLDocument parses the XML
+func ParseXMLDocument(data []byte) (*XMLDocument, error) {
+	doc := &XMLDocument{}
+	text := string(data)
+	trimmed := strings.TrimLeft(text, "\ufeff \t\r\n")
+	if strings.HasPrefix(trimmed, "<?xml") {
+		i := strings.Index(trimmed, "?>")
+		if i < 0 {
+			return nil, fmt.Errorf("Failed to parse XML as the declaration is not terminated")
+		}
+		doc.Declaration = trimmed[:i+2]
+		text = trimmed[i+2:]
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	stack := []*XMLElement{}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse XML due to %v", err)
+		}
+		var node interface{}
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &XMLElement{
+				Name: xmlName(t.Name),
+				Attr: append([]xml.Attr{}, t.Attr...),
+			}
+			if len(stack) == 0 && doc.Root == nil {
+				doc.Root = element
+			} else if len(stack) == 0 {
+				return nil, fmt.Errorf("Failed to parse XML as it has more than one root element")
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, element)
+			}
+			stack = append(stack, element)
+			continue
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("Failed to parse XML due to an unexpected end element %s", xmlName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		case xml.CharData:
+			node = t.Copy()
+		case xml.Comment:
+			node = t.Copy()
+		case xml.ProcInst:
+			node = t.Copy()
+		case xml.Directive:
+			node = t.Copy()
+		}
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		} else if doc.Root == nil {
+			doc.before = append(doc.before, node)
+		} else {
+			doc.after = append(doc.after, node)
+		}
+	}
+	if doc.Root == nil {
+		return nil, fmt.Errorf("Failed to parse XML as it has no root element")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("Failed to parse XML as element %s is not closed", stack[len(stack)-1].Name)
+	}
+	return doc, nil
+}
+
+// Bytes returns the XML of the document
+func (d *XMLDocument) Bytes() []byte {
+	var buffer bytes.Buffer
+	if d.Declaration != "" {
+		buffer.WriteString(d.Declaration)
+		if len(d.before) == 0 {
+			buffer.WriteString("\n")
+		}
+	}
+	for _, node := range d.before {
+		writeXMLNode(&buffer, node)
+	}
+	writeXMLNode(&buffer, d.Root)
+	for _, node := range d.after {
+		writeXMLNode(&buffer, node)
+	}
+	return buffer.Bytes()
+}
+
+// Get returns the text of the first element or attribute matching the path
+func (d *XMLDocument) Get(path string) (string, error) {
+	elements, step, err := d.selectPath(path)
+	if err != nil {
+		return "", err
+	}
+	for _, element := range elements {
+		if step.attribute != "" {
+			if value, ok := element.attrValue(step.attribute); ok {
+				return value, nil
+			}
+			continue
+		}
+		return element.Text(), nil
+	}
+	return "", fmt.Errorf("No XML matches %s", path)
+}
+
+// Set sets the text of the elements or the attributes matching the path returning how many were changed.
+// If nothing matches but the parent of a missing element or attribute does then it is created
+func (d *XMLDocument) Set(path string, value string) (int, error) {
+	elements, step, err := d.selectPath(path)
+	if err != nil {
+		return 0, err
+	}
+	if step.attribute != "" {
+		for _, element := range elements {
+			element.setAttr(step.attribute, value)
+		}
+		if len(elements) == 0 {
+			return 0, fmt.Errorf("No XML element matches %s", path)
+		}
+		return len(elements), nil
+	}
+	if len(elements) == 0 {
+		created, err := d.create(path)
+		if err != nil {
+			return 0, err
+		}
+		elements = []*XMLElement{created}
+	}
+	for _, element := range elements {
+		err = element.SetText(value)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to set %s due to %v", path, err)
+		}
+	}
+	return len(elements), nil
+}
+
+// Text returns the text inside the element ignoring any child elements
+func (e *XMLElement) Text() string {
+	var buffer bytes.Buffer
+	for _, child := range e.Children {
+		if text, ok := child.(xml.CharData); ok {
+			buffer.Write(text)
+		}
+	}
+	return strings.TrimSpace(buffer.String())
+}
+
+// SetText replaces the text of the element which must not contain other elements
+func (e *XMLElement) SetText(text string) error {
+	for _, child := range e.Children {
+		if element, ok := child.(*XMLElement); ok {
+			return fmt.Errorf("element %s contains element %s", e.Name, element.Name)
+		}
+	}
+	e.Children = []interface{}{xml.CharData(text)}
+	return nil
+}
+
+func (e *XMLElement) attrValue(name string) (string, bool) {
+	for _, attr := range e.Attr {
+		if xmlName(attr.Name) == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+func (e *XMLElement) setAttr(name string, value string) {
+	for i, attr := range e.Attr {
+		if xmlName(attr.Name) == name {
+			e.Attr[i].Value = value
+			return
+		}
+	}
+	e.Attr = append(e.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+func (e *XMLElement) childElements() []*XMLElement {
+	answer := []*XMLElement{}
+	for _, child := range e.Children {
+		if element, ok := child.(*XMLElement); ok {
+			answer = append(answer, element)
+		}
+	}
+	return answer
+}
+
+func (e *XMLElement) descendants() []*XMLElement {
+	answer := []*XMLElement{}
+	for _, child := range e.childElements() {
+		answer = append(answer, child)
+		answer = append(answer, child.descendants()...)
+	}
+	return answer
+}
+
+// selectPath returns the elements matching the path and the last step so callers can tell if it selects an attribute
+func (d *XMLDocument) selectPath(path string) ([]*XMLElement, *xmlStep, error) {
+	steps, err := parseXMLPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := steps[len(steps)-1]
+	elementSteps := steps
+	if last.attribute != "" {
+		elementSteps = steps[:len(steps)-1]
+	}
+	// the document node is the parent of the root element
+	current := []*XMLElement{{Children: []interface{}{d.Root}}}
+	for _, step := range elementSteps {
+		next := []*XMLElement{}
+		for _, element := range current {
+			candidates := element.childElements()
+			if step.descendant {
+				candidates = element.descendants()
+			}
+			next = append(next, step.filter(candidates)...)
+		}
+		current = next
+	}
+	if len(elementSteps) == 0 {
+		current = []*XMLElement{d.Root}
+	}
+	return current, last, nil
+}
+
+// create creates the last element of the path inside the element matching the rest of the path
+func (d *XMLDocument) create(path string) (*XMLElement, error) {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return nil, fmt.Errorf("No XML element matches %s", path)
+	}
+	parentPath, name := path[:i], path[i+1:]
+	if !isXMLName(name) {
+		return nil, fmt.Errorf("No XML element matches %s", path)
+	}
+	parents, step, err := d.selectPath(parentPath)
+	if err != nil {
+		return nil, err
+	}
+	if step.attribute != "" || len(parents) != 1 {
+		return nil, fmt.Errorf("No XML element matches %s", path)
+	}
+	parent := parents[0]
+	element := &XMLElement{Name: name}
+	parent.Children = append(parent.Children, element)
+	return element, nil
+}
+
+func (s *xmlStep) filter(elements []*XMLElement) []*XMLElement {
+	answer := []*XMLElement{}
+	for _, element := range elements {
+		if s.name == "*" || element.Name == s.name {
+			answer = append(answer, element)
+		}
+	}
+	for _, predicate := range s.predicates {
+		if predicate.index > 0 {
+			if predicate.index > len(answer) {
+				return nil
+			}
+			answer = answer[predicate.index-1 : predicate.index]
+			continue
+		}
+		matches := []*XMLElement{}
+		for _, element := range answer {
+			if predicate.matches(element) {
+				matches = append(matches, element)
+			}
+		}
+		answer = matches
+	}
+	return answer
+}
+
+func (p *xmlPredicate) matches(element *XMLElement) bool {
+	if p.attribute != "" {
+		value, ok := element.attrValue(p.attribute)
+		return ok && value == p.value
+	}
+	for _, child := range element.childElements() {
+		if child.Name == p.child && child.Text() == p.value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseXMLPath parses the subset of XPath used by the steps: absolute paths of element names or `*`
+// where `//` matches descendants, with `[child='value']`, `[@attr='value']` and `[n]` predicates
+// and an optional final `@attr` step
+func parseXMLPath(path string) ([]*xmlStep, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("Invalid XML path %s as it must start with /", path)
+	}
+	steps := []*xmlStep{}
+	rest := path
+	for rest != "" {
+		step := &xmlStep{}
+		if strings.HasPrefix(rest, "//") {
+			step.descendant = true
+			rest = rest[2:]
+		} else if strings.HasPrefix(rest, "/") {
+			rest = rest[1:]
+		} else {
+			return nil, fmt.Errorf("Invalid XML path %s at %s", path, rest)
+		}
+		end := 0
+		for end < len(rest) && rest[end] != '/' && rest[end] != '[' {
+			end++
+		}
+		name := rest[:end]
+		rest = rest[end:]
+		if strings.HasPrefix(name, "@") {
+			step.attribute = name[1:]
+			if step.attribute == "" || rest != "" || step.descendant {
+				return nil, fmt.Errorf("Invalid XML path %s as an attribute must be the last step", path)
+			}
+		} else if name != "*" && !isXMLName(name) {
+			return nil, fmt.Errorf("Invalid XML path %s as %q is not an element name", path, name)
+		}
+		step.name = name
+		for strings.HasPrefix(rest, "[") {
+			predicate, remaining, err := parseXMLPredicate(rest)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid XML path %s due to %v", path, err)
+			}
+			step.predicates = append(step.predicates, predicate)
+			rest = remaining
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("Invalid XML path %s", path)
+	}
+	return steps, nil
+}
+
+// parseXMLPredicate parses the predicate at the start of the text returning the rest of the text
+func parseXMLPredicate(text string) (xmlPredicate, string, error) {
+	predicate := xmlPredicate{}
+	var quote byte
+	end := -1
+	for i := 1; i < len(text); i++ {
+		ch := text[i]
+		if quote != 0 {
+			if ch == quote {
+				quote = 0
+			}
+		} else if ch == '\'' || ch == '"' {
+			quote = ch
+		} else if ch == ']' {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return predicate, "", fmt.Errorf("predicate %s is not terminated", text)
+	}
+	body := strings.TrimSpace(text[1:end])
+	rest := text[end+1:]
+	if index, err := strconv.Atoi(body); err == nil {
+		if index < 1 {
+			return predicate, "", fmt.Errorf("index %d must be at least 1", index)
+		}
+		predicate.index = index
+		return predicate, rest, nil
+	}
+	i := strings.Index(body, "=")
+	if i < 0 {
+		return predicate, "", fmt.Errorf("predicate [%s] should be a number or name='value'", body)
+	}
+	name := strings.TrimSpace(body[:i])
+	value := strings.TrimSpace(body[i+1:])
+	if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+		return predicate, "", fmt.Errorf("the value of predicate [%s] should be quoted", body)
+	}
+	predicate.value = value[1 : len(value)-1]
+	if strings.HasPrefix(name, "@") {
+		predicate.attribute = name[1:]
+	} else {
+		predicate.child = name
+	}
+	return predicate, rest, nil
+}
+
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, ch := range name {
+		if !(ch == '-' || ch == '_' || ch == '.' || ch == ':' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func writeXMLNode(buffer *bytes.Buffer, node interface{}) {
+	switch n := node.(type) {
+	case *XMLElement:
+		buffer.WriteString("<" + n.Name)
+		for _, attr := range n.Attr {
+			buffer.WriteString(" " + xmlName(attr.Name) + "=\"" + xmlAttrEscaper.Replace(attr.Value) + "\"")
+		}
+		if len(n.Children) == 0 {
+			buffer.WriteString("/>")
+			return
+		}
+		buffer.WriteString(">")
+		for _, child := range n.Children {
+			writeXMLNode(buffer, child)
+		}
+		buffer.WriteString("</" + n.Name + ">")
+	case xml.CharData:
+		buffer.WriteString(xmlTextEscaper.Replace(string(n)))
+	case xml.Comment:
+		buffer.WriteString("<!--")
+		buffer.Write(n)
+		buffer.WriteString("-->")
+	case xml.ProcInst:
+		buffer.WriteString("<?" + n.Target + " ")
+		buffer.Write(n.Inst)
+		buffer.WriteString("?>")
+	case xml.Directive:
+		buffer.WriteString("<!")
+		buffer.Write(n)
+		buffer.WriteString(">")
+	}
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const testJobConfig = `<?xml version='1.1' encoding='UTF-8'?>
+<!-- a job config -->
+<flow-definition plugin="workflow-job@2.12">
+  <description>my job</description>
+  <disabled>false</disabled>
+  <properties>
+    <hudson.model.ParametersDefinitionProperty>
+      <parameterDefinitions>
+        <hudson.model.StringParameterDefinition>
+          <name>repository</name>
+          <defaultValue>fabric8io/example</defaultValue>
+        </hudson.model.StringParameterDefinition>
+        <hudson.model.StringParameterDefinition>
+          <name>pipeline</name>
+          <defaultValue>Release</defaultValue>
+        </hudson.model.StringParameterDefinition>
+      </parameterDefinitions>
+    </hudson.model.ParametersDefinitionProperty>
+  </properties>
+  <definition class="CpsFlowDefinition" plugin="workflow-cps@2.36">
+    <script>if (a &lt; b &amp;&amp; c) { echo "hi" }</script>
+  </definition>
+</flow-definition>
+`
+
+func TestXMLDocumentWritesBackUnchanged(t *testing.T) {
+	doc, err := ParseXMLDocument([]byte(testJobConfig))
+	if err != nil {
+		t.Fatalf("ParseXMLDocument failed: %v", err)
+	}
+	if actual := string(doc.Bytes()); actual != testJobConfig {
+		t.Errorf("Bytes() changed the document:\n%s", actual)
+	}
+}
+
+func TestXMLDocumentGet(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/flow-definition/description", "my job"},
+		{"/*/disabled", "false"},
+		{"/flow-definition/@plugin", "workflow-job@2.12"},
+		{"//defaultValue", "fabric8io/example"},
+		{"//hudson.model.StringParameterDefinition[2]/name", "pipeline"},
+		{"//hudson.model.StringParameterDefinition[name='pipeline']/defaultValue", "Release"},
+		{`//hudson.model.StringParameterDefinition[name="repository"]/defaultValue`, "fabric8io/example"},
+		{"//definition[@class='CpsFlowDefinition']/@plugin", "workflow-cps@2.36"},
+		{"/flow-definition/definition/script", `if (a < b && c) { echo "hi" }`},
+	}
+	doc, err := ParseXMLDocument([]byte(testJobConfig))
+	if err != nil {
+		t.Fatalf("ParseXMLDocument failed: %v", err)
+	}
+	for _, test := range tests {
+		actual, err := doc.Get(test.path)
+		if err != nil {
+			t.Errorf("Get(%q) failed: %v", test.path, err)
+		} else if actual != test.expected {
+			t.Errorf("Get(%q) = %q, expected %q", test.path, actual, test.expected)
+		}
+	}
+}
+
+func TestXMLDocumentGetErrors(t *testing.T) {
+	paths := []string{
+		"flow-definition",
+		"/flow-definition/missing",
+		"//hudson.model.StringParameterDefinition[3]/name",
+		"//hudson.model.StringParameterDefinition[0]",
+		"//hudson.model.StringParameterDefinition[name=pipeline]",
+		"//hudson.model.StringParameterDefinition[name='pipeline'",
+		"/flow-definition/@plugin/description",
+		"/flow-definition/bad name",
+	}
+	doc, err := ParseXMLDocument([]byte(testJobConfig))
+	if err != nil {
+		t.Fatalf("ParseXMLDocument failed: %v", err)
+	}
+	for _, path := range paths {
+		_, err := doc.Get(path)
+		if err == nil {
+			t.Errorf("Get(%q) should have failed", path)
+		}
+	}
+}
+
+func TestXMLDocumentSet(t *testing.T) {
+	tests := []struct {
+		path    string
+		value   string
+		changed int
+		// expected is a fragment the written back document should contain
+		expected string
+	}{
+		{"/*/disabled", "true", 1, "<disabled>true</disabled>"},
+		{"//hudson.model.StringParameterDefinition[name='pipeline']/defaultValue", "CD", 1, "<defaultValue>CD</defaultValue>"},
+		{"//defaultValue", "x", 2, "<defaultValue>x</defaultValue>\n        </hudson.model.StringParameterDefinition>\n        <hudson.model.StringParameterDefinition>\n          <name>pipeline</name>\n          <defaultValue>x</defaultValue>"},
+		{"/flow-definition/@plugin", `a"b`, 1, `<flow-definition plugin="a&quot;b">`},
+		{"/flow-definition/definition/@sandbox", "true", 1, `<definition class="CpsFlowDefinition" plugin="workflow-cps@2.36" sandbox="true">`},
+		{"/flow-definition/keepDependencies", "false", 1, "\n<keepDependencies>false</keepDependencies></flow-definition>"},
+		{"/flow-definition/description", "a < b & c", 1, "<description>a &lt; b &amp; c</description>"},
+	}
+	for _, test := range tests {
+		doc, err := ParseXMLDocument([]byte(testJobConfig))
+		if err != nil {
+			t.Fatalf("ParseXMLDocument failed: %v", err)
+		}
+		changed, err := doc.Set(test.path, test.value)
+		if err != nil {
+			t.Errorf("Set(%q) failed: %v", test.path, err)
+			continue
+		}
+		if changed != test.changed {
+			t.Errorf("Set(%q) changed %d, expected %d", test.path, changed, test.changed)
+		}
+		actual := string(doc.Bytes())
+		if !strings.Contains(actual, test.expected) {
+			t.Errorf("Set(%q) wrote:\n%s\nwhich does not contain:\n%s", test.path, actual, test.expected)
+		}
+		if !strings.HasPrefix(actual, "<?xml version='1.1' encoding='UTF-8'?>\n<!-- a job config -->\n") {
+			t.Errorf("Set(%q) did not keep the declaration and comment:\n%s", test.path, actual)
+		}
+		reparsed, err := ParseXMLDocument(doc.Bytes())
+		if err != nil {
+			t.Errorf("Set(%q) wrote XML which does not parse: %v", test.path, err)
+			continue
+		}
+		value, err := reparsed.Get(test.path)
+		if err != nil || value != test.value {
+			t.Errorf("Get(%q) after Set = %q, %v, expected %q", test.path, value, err, test.value)
+		}
+	}
+}
+
+func TestXMLDocumentSetErrors(t *testing.T) {
+	paths := []string{
+		"/flow-definition/properties",
+		"/flow-definition/missing/child",
+		"/missing/@attr",
+		"//parameterDefinitions/hudson.model.StringParameterDefinition/extra",
+	}
+	for _, path := range paths {
+		doc, err := ParseXMLDocument([]byte(testJobConfig))
+		if err != nil {
+			t.Fatalf("ParseXMLDocument failed: %v", err)
+		}
+		_, err = doc.Set(path, "value")
+		if err == nil {
+			t.Errorf("Set(%q) should have failed", path)
+		}
+	}
+}
+
+func TestParseXMLDocumentErrors(t *testing.T) {
+	documents := []string{
+		"",
+		"<?xml version='1.0'",
+		"<a><b></a>",
+		"<a></a><b></b>",
+		"<a>",
+	}
+	for _, document := range documents {
+		_, err := ParseXMLDocument([]byte(document))
+		if err == nil {
+			t.Errorf("ParseXMLDocument(%q) should have failed", document)
+		}
+	}
+}